@@ -10,8 +10,6 @@ import (
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/magefile/mage/mg"
-	"github.com/magefile/mage/sh"
-	"github.com/magefile/mage/target"
 	"github.com/rkennedy/magehelper/iters"
 )
 
@@ -40,13 +38,14 @@ func GetDependencies(
 	files func(pkg Package) []string,
 	imports func(pkg Package) []string,
 ) (result []string) {
-	processedPackages := mapset.NewThreadUnsafeSetWithSize[string](len(Packages))
+	packages := LoadedPackages()
+	processedPackages := mapset.NewThreadUnsafeSetWithSize[string](len(packages))
 	worklist := mapset.NewSet(baseMod)
 
 	for current, ok := worklist.Pop(); ok; current, ok = worklist.Pop() {
 		if processedPackages.Add(current) {
 			// It's a package we haven't already processed.
-			if pkg, ok := Packages[current]; ok {
+			if pkg, ok := packages[current]; ok {
 				result = append(result, files(pkg)...)
 				worklist.Append(imports(pkg)...)
 			}
@@ -66,17 +65,25 @@ func buildBuildCommandLine(exe string, pkg string, tags []string) []string {
 
 // Build builds the current package with the given tags and writes the result to the given binary location.
 func Build(ctx context.Context, exe string, tags ...string) error {
-	mg.CtxDeps(ctx, LoadDependencies)
+	task := newFuncTask("Build "+exe, Build, exe, tags)
+	return RunTask(ctx, task, func(ctx context.Context) error {
+		return buildExe(ctx, exe, tags)
+	})
+}
+
+func buildExe(ctx context.Context, exe string, tags []string) error {
+	Deps(ctx, LoadDependencies)
 	pkg, err := BasePackage()
 	if err != nil {
 		return err
 	}
 	deps := GetDependencies(pkg, Package.SourceFiles, Package.SourceImportPackages)
-	newer, err := target.Path(exe, deps...)
+	TaskLogger(ctx).Debug("checking build", "package", pkg, attrBinary, exe, "inputs", len(deps))
+	newer, err := Stale(ctx, exe, deps...)
 	if err != nil || !newer {
 		return err
 	}
-	return sh.RunV(mg.GoCmd(), buildBuildCommandLine(exe, pkg, tags)...)
+	return RunV(ctx, mg.GoCmd(), buildBuildCommandLine(exe, pkg, tags)...)
 }
 
 func buildGinkgoBuildCommandLine(exe string, pkg string, tags ...string) []string {
@@ -120,20 +127,24 @@ func (tb *TestBuilder) ID() string {
 // Run implements [mg.Fn]. If the test binary for the package needs building, then it gets built using the configured
 // build tags, outputting <package-name>.test in the package director.
 func (tb *TestBuilder) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx, LoadDependencies)
+	return RunTask(ctx, tb, tb.execute)
+}
+
+func (tb *TestBuilder) execute(ctx context.Context) error {
+	Deps(ctx, LoadDependencies)
 	deps := GetDependencies(tb.pkg, Package.TestFiles, Package.TestImportPackages)
 	if len(deps) == 0 {
 		return nil
 	}
 
-	info := Packages[tb.pkg]
+	info := LoadedPackages()[tb.pkg]
 	exe := info.TestBinary()
 
-	newer, err := target.Path(exe, deps...)
+	newer, err := Stale(ctx, exe, deps...)
 	if err != nil || !newer {
 		return err
 	}
-	return sh.RunV(mg.GoCmd(), buildTestCommandLine(exe, tb.pkg, tb.tags...)...)
+	return RunV(ctx, mg.GoCmd(), buildTestCommandLine(exe, tb.pkg, tb.tags...)...)
 }
 
 // UseGinkgo configures the dependency to use Ginkgo to build the test instead of "go test -c." Provide the path to the
@@ -160,23 +171,27 @@ var _ mg.Fn = &GinkgoTestBuilder{}
 
 // Run implements [mg.Fn]. It runs "ginkgo build" to build the tests for the package.
 func (sgtb *GinkgoTestBuilder) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx,
+	return RunTask(ctx, sgtb, sgtb.execute)
+}
+
+func (sgtb *GinkgoTestBuilder) execute(ctx context.Context) error {
+	Deps(ctx,
 		LoadDependencies,
 		Install(sgtb.bin, "github.com/onsi/ginkgo/v2/ginkgo"),
 	)
 	// Find Package with RelPath == sgtb.pkg
-	info, ok := iters.SliceSelectFirst(maps.Values(Packages), func(info Package) bool {
+	info, ok := iters.SliceSelectFirst(maps.Values(LoadedPackages()), func(info Package) bool {
 		return info.RelPath() == sgtb.pkg
 	})
 	if !ok {
 		return fmt.Errorf("package %s not found", sgtb.pkg)
 	}
 	deps := GetDependencies(info.ImportPath, Package.TestFiles, Package.TestImportPackages)
-	needsBuild, err := target.Path(info.TestBinary(), deps...)
+	needsBuild, err := Stale(ctx, info.TestBinary(), deps...)
 	if err != nil || !needsBuild {
 		return err
 	}
-	return sh.RunV(sgtb.bin, buildGinkgoBuildCommandLine(info.TestBinary(), sgtb.pkg, sgtb.tags...)...)
+	return RunV(ctx, sgtb.bin, buildGinkgoBuildCommandLine(info.TestBinary(), sgtb.pkg, sgtb.tags...)...)
 }
 
 // AllGinkgoTestBuilder implements [mg.Fn] to use Ginkgo to build all the tests using build tags specified by
@@ -189,18 +204,22 @@ type AllGinkgoTestBuilder struct {
 var _ mg.Fn = &AllGinkgoTestBuilder{}
 
 func packagesHavingTests() iter.Seq[Package] {
-	return iters.Filter(maps.Values(Packages), Package.HasTest)
+	return iters.Filter(maps.Values(LoadedPackages()), Package.HasTest)
 }
 
 // Run implements [mb.Fn]. It determines the list of tests in the project and runs them all on a single Ginkgo command.
 func (agtb *AllGinkgoTestBuilder) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx,
+	return RunTask(ctx, agtb, agtb.execute)
+}
+
+func (agtb *AllGinkgoTestBuilder) execute(ctx context.Context) error {
+	Deps(ctx,
 		LoadDependencies,
 	)
 	deps := iters.SliceTransform(packagesHavingTests(), func(pkg Package) any {
 		return BuildTest(pkg.RelPath(), agtb.tags...).UseGinkgo(agtb.bin)
 	})
-	mg.CtxDeps(ctx, slices.Collect(deps)...)
+	Deps(ctx, slices.Collect(deps)...)
 	return nil
 }
 
@@ -231,12 +250,16 @@ func filter[T any](src iter.Seq[T], pred func(T) bool) iter.Seq[T] {
 
 // Run implements [mg.Fn]. It determines the list of tests in the project and runs them all in parallel.
 func (atb *AllTestBuilder) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx, LoadDependencies)
+	return RunTask(ctx, atb, atb.execute)
+}
+
+func (atb *AllTestBuilder) execute(ctx context.Context) error {
+	Deps(ctx, LoadDependencies)
 	tests := []any{}
-	for mod := range filter(maps.Values(Packages), Package.HasTest) {
+	for mod := range filter(maps.Values(LoadedPackages()), Package.HasTest) {
 		tests = append(tests, BuildTest(mod.ImportPath, atb.tags...))
 	}
-	mg.CtxDeps(ctx, tests...)
+	Deps(ctx, tests...)
 	return nil
 }
 
@@ -286,9 +309,13 @@ func (tr *testRunner) ID() string {
 
 // Run implements [mg.Fn]. It runs the package's test with "go test."
 func (tr *testRunner) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx, BuildTest(tr.pkg, tr.tags...))
+	return RunTask(ctx, tr, tr.execute)
+}
+
+func (tr *testRunner) execute(ctx context.Context) error {
+	Deps(ctx, BuildTest(tr.pkg, tr.tags...))
 
-	return sh.RunV(mg.GoCmd(), runTestCommandLine(tr.pkg, tr.tags)...)
+	return RunV(ctx, mg.GoCmd(), runTestCommandLine(tr.pkg, tr.tags)...)
 }
 
 // runTest returns a [mg.Fn] that will run the tests for the given package, subject to the given build tags.
@@ -308,11 +335,15 @@ var _ mg.Fn = &AllGinkgoTestRunner{}
 // Run implements [mg.Fn]. It uses Ginkgo to build test binaries for all applicable packages in the project (as by
 // [AllGinkgoTestBuilder], and then uses Ginkgo to run them all.
 func (agtr *AllGinkgoTestRunner) Run(ctx context.Context) error {
+	return RunTask(ctx, agtr, agtr.execute)
+}
+
+func (agtr *AllGinkgoTestRunner) execute(ctx context.Context) error {
 	// It's technically not necessary to build the tests before running them; "ginkgo run" would build them anyway.
 	// However, we specify BuildTests as a dependency so that _all_ the tests get built before _any_ of them start
 	// running. That makes the output cleaner because lengthy test output doesn't push any build failures off the
 	// top of the screen.
-	mg.CtxDeps(ctx,
+	Deps(ctx,
 		LoadDependencies,
 		Install(agtr.bin, "github.com/onsi/ginkgo/v2/ginkgo"),
 		BuildTests(agtr.tags...).UseGinkgo(agtr.bin),
@@ -325,10 +356,10 @@ func (agtr *AllGinkgoTestRunner) Run(ctx context.Context) error {
 		args = append(args, "-p")
 	}
 	args = append(args, formatTags(ginkgoTagOpt, agtr.tags)...)
-	for info := range filter(maps.Values(Packages), Package.HasTest) {
+	for info := range filter(maps.Values(LoadedPackages()), Package.HasTest) {
 		args = append(args, info.TestBinary())
 	}
-	return sh.Run(agtr.bin, args...)
+	return Run(ctx, agtr.bin, args...)
 }
 
 // Parallel instructs the test runner to use the "ginkgo -p" option to run tests in parallel. Beware that running in
@@ -360,15 +391,19 @@ func (*AllTestRunner) ID() string {
 // tests are built before any begin running; this makes the output cleaner because any lengthy test output doesn't push
 // any build failures off the top of the screen.
 func (atr *AllTestRunner) Run(ctx context.Context) error {
+	return RunTask(ctx, atr, atr.execute)
+}
+
+func (atr *AllTestRunner) execute(ctx context.Context) error {
 	// It's technically not necessary to build the tests before running them; "go test" will build them anyway.
 	// However, we specify BuildTests as a dependency so that _all_ the tests get built before _any_ of them start
 	// running.
-	mg.CtxDeps(ctx, LoadDependencies, BuildTests(atr.tags...))
+	Deps(ctx, LoadDependencies, BuildTests(atr.tags...))
 	tests := []any{}
-	for info := range filter(maps.Values(Packages), Package.HasTest) {
+	for info := range filter(maps.Values(LoadedPackages()), Package.HasTest) {
 		tests = append(tests, runTest(info.ImportPath, atr.tags...))
 	}
-	mg.CtxDeps(ctx, tests...)
+	Deps(ctx, tests...)
 	return nil
 }
 
@@ -385,10 +420,3 @@ func (atr *AllTestRunner) UseGinkgo(ginkgoBin string) *AllGinkgoTestRunner {
 func Test(tags ...string) *AllTestRunner {
 	return &AllTestRunner{tags: tags}
 }
-
-// LogV prints the message with [fmt.Printf] if [mg.Verbose] is true.
-func LogV(msg string, args ...any) {
-	if mg.Verbose() {
-		_, _ = fmt.Printf(msg, args...)
-	}
-}
@@ -3,6 +3,7 @@ package magehelper_test
 
 import (
 	"context"
+	"sync"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -26,3 +27,36 @@ var _ = Describe("GetDependencies", func() {
 		))
 	})
 })
+
+var _ = Describe("BuildTests", func() {
+	BeforeEach(func() {
+		magehelper.SetDryRun(true)
+		DeferCleanup(magehelper.SetDryRun, false)
+	})
+
+	It("reads the packages safely while another task reloads them", func(ctx context.Context) {
+		// Like a code generator running alongside the test build, one goroutine keeps reloading the packages while the
+		// other finds the tests to build. Run with -race to detect unguarded access.
+		built := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			defer GinkgoRecover()
+			for {
+				Expect(magehelper.ReloadPackages(ctx)).To(Succeed())
+				select {
+				case <-built:
+					return
+				default:
+				}
+			}
+		})
+		wg.Go(func() {
+			defer GinkgoRecover()
+			defer close(built)
+			for range 3 {
+				Expect(magehelper.BuildTests().Run(ctx)).To(Succeed())
+			}
+		})
+		wg.Wait()
+	})
+})
@@ -0,0 +1,130 @@
+package magehelper
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/magefile/mage/sh"
+)
+
+// DryRunEnv is the environment variable that enables dry-run mode when set to a true value, as interpreted by
+// [strconv.ParseBool]. Mage doesn't have a dry-run flag of its own, so set this variable on the mage command line
+// instead, or call [SetDryRun] from the magefile.
+const DryRunEnv = "MAGEHELPER_DRYRUN"
+
+var dryRun atomic.Bool
+
+// SetDryRun enables or disables dry-run mode for the whole process. In dry-run mode, magehelper tasks still resolve
+// their dependencies and decide whether their outputs are stale, but instead of running any commands, they print the
+// commands they would have run.
+func SetDryRun(enabled bool) {
+	dryRun.Store(enabled)
+}
+
+// DryRun reports whether dry-run mode is active, either because of [SetDryRun] or because of [DryRunEnv].
+func DryRun() bool {
+	if dryRun.Load() {
+		return true
+	}
+	b, _ := strconv.ParseBool(os.Getenv(DryRunEnv))
+	return b
+}
+
+// LogDryRun logs the message with the given attributes at info level if [DryRun] is true. Tasks use it to report
+// decisions that would otherwise go unmentioned, such as that a file is up to date.
+func LogDryRun(ctx context.Context, msg string, args ...any) {
+	if DryRun() {
+		TaskLogger(ctx).Info(msg, append([]any{slog.Bool("dry_run", true)}, args...)...)
+	}
+}
+
+// pendingFiles holds the files that tasks would have created or replaced if dry-run mode weren't active, such as tools
+// that would have been installed.
+var pendingFiles sync.Map
+
+// markPending records, in dry-run mode, that a task would have created or replaced the given file. Staleness checks
+// treat such files as newer than every output, since they would have been by the time the checks ran for real.
+func markPending(file string) {
+	if DryRun() {
+		pendingFiles.Store(filepath.Clean(file), true)
+	}
+}
+
+// pending reports whether a task would have created or replaced the given file if not for dry-run mode.
+func pending(file string) bool {
+	_, found := pendingFiles.Load(filepath.Clean(file))
+	return found
+}
+
+// quoteArgs formats a command line for display, quoting only those arguments that need it.
+func quoteArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// extraEnv returns the entries of env that aren't already part of this process's environment. Commands usually get
+// the whole environment plus a few additions, and only the additions are interesting to show.
+func extraEnv(env []string) []string {
+	base := os.Environ()
+	return slices.DeleteFunc(slices.Clone(env), func(entry string) bool {
+		return slices.Contains(base, entry)
+	})
+}
+
+func logCommand(ctx context.Context, dir string, env []string, args []string) {
+	if dir == "" {
+		dir = "."
+	}
+	LogDryRun(ctx, "would run", "dir", dir, "env", quoteArgs(extraEnv(env)), "cmd", quoteArgs(args))
+}
+
+// RunCmd runs the given command. In dry-run mode, it logs the command's working directory, the environment variables
+// it adds, and its command line instead.
+func RunCmd(ctx context.Context, c *exec.Cmd) error {
+	if DryRun() {
+		logCommand(ctx, c.Dir, c.Env, c.Args)
+		return nil
+	}
+	return c.Run()
+}
+
+// RunV is like [sh.RunV], but in dry-run mode, it logs the command line instead of running it.
+func RunV(ctx context.Context, cmd string, args ...string) error {
+	if DryRun() {
+		logCommand(ctx, "", nil, append([]string{cmd}, args...))
+		return nil
+	}
+	return sh.RunV(cmd, args...)
+}
+
+// Run is like [sh.Run], but in dry-run mode, it logs the command line instead of running it.
+func Run(ctx context.Context, cmd string, args ...string) error {
+	if DryRun() {
+		logCommand(ctx, "", nil, append([]string{cmd}, args...))
+		return nil
+	}
+	return sh.Run(cmd, args...)
+}
+
+// Rm is like [sh.Rm], but in dry-run mode, it logs the name of the file that would be removed instead of removing it.
+func Rm(ctx context.Context, path string) error {
+	if DryRun() {
+		LogDryRun(ctx, "would remove", "file", path)
+		return nil
+	}
+	return sh.Rm(path)
+}
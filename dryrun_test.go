@@ -0,0 +1,40 @@
+package magehelper_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+)
+
+var _ = Describe("DryRun", func() {
+	BeforeEach(func() {
+		magehelper.SetDryRun(true)
+		DeferCleanup(magehelper.SetDryRun, false)
+	})
+
+	It("doesn't run commands", func(ctx context.Context) {
+		Expect(magehelper.RunV(ctx, "false")).To(Succeed())
+	})
+
+	It("still decides staleness", func(ctx context.Context) {
+		Expect(magehelper.Stale(ctx, filepath.Join(GinkgoT().TempDir(), "missing"), "build.go")).To(BeTrue())
+	})
+
+	It("treats a tool that would be installed as newer than its outputs", func(ctx context.Context) {
+		dir := GinkgoT().TempDir()
+		bin := filepath.Join(dir, "mage")
+		Expect(magehelper.Install(bin, "github.com/magefile/mage").Run(ctx)).To(Succeed())
+		Expect(bin).NotTo(BeAnExistingFile())
+		Expect(magehelper.Stale(ctx, "build.go", bin)).To(BeTrue())
+	})
+
+	It("doesn't build", func(ctx context.Context) {
+		exe := filepath.Join(GinkgoT().TempDir(), "magehelper")
+		Expect(magehelper.Build(ctx, exe)).To(Succeed())
+		Expect(exe).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,275 @@
+package magehelper
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// defaultGithubAPIURL is the base URL of the public Github API.
+	defaultGithubAPIURL = "https://api.github.com"
+	// GithubAPIURLEnv is the environment variable that sets the base URL of the Github API when a task doesn't set
+	// one. Github Actions sets it for every workflow, including on Github Enterprise Server.
+	GithubAPIURLEnv = "GITHUB_API_URL"
+	// GithubTokenEnv is the environment variable holding a token for authenticating Github API requests.
+	GithubTokenEnv = "GITHUB_TOKEN"
+	// golangciLintRepo is the path of golangci-lint's repository in the Github API.
+	golangciLintRepo = "/repos/golangci/golangci-lint"
+	// releaseInfoFile is the name of the file in the cache that holds a release's information.
+	releaseInfoFile = "release.json"
+	// cacheDirMode is the permissions for directories in the download cache.
+	cacheDirMode fs.FileMode = 0o755
+	// cacheFileMode is the permissions for files in the download cache.
+	cacheFileMode fs.FileMode = 0o644
+)
+
+// releaseSource fetches release information and assets from Github, or from a server that provides the same API,
+// optionally keeping them in a local cache.
+type releaseSource struct {
+	apiURL   string
+	client   *http.Client
+	cacheDir string
+}
+
+// baseURL returns the base URL of the API without a trailing slash.
+func (src *releaseSource) baseURL() string {
+	return strings.TrimSuffix(cmp.Or(src.apiURL, os.Getenv(GithubAPIURLEnv), defaultGithubAPIURL), "/")
+}
+
+// newRequest creates a request for the given URL. Requests to the API carry the token from [GithubTokenEnv], but
+// downloads from other hosts don't, so the token isn't revealed to them.
+func (src *releaseSource) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(url, src.baseURL()+"/") {
+		return req, nil
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv(GithubTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// open requests the given URL and returns the response body for the caller to close. It fails unless the server
+// reports success.
+func (src *releaseSource) open(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := src.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cmp.Or(src.client, http.DefaultClient).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not download %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Join(fmt.Errorf("Could not download %s: %s", url, resp.Status), resp.Body.Close())
+	}
+	return resp.Body, nil
+}
+
+// get fetches the given URL and returns the response body. It's for small responses, such as release information.
+func (src *releaseSource) get(ctx context.Context, url string) ([]byte, error) {
+	body, err := src.open(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// cacheFile returns the location in the cache of the named file for the given release tag.
+func (src *releaseSource) cacheFile(tag, name string) string {
+	return filepath.Join(src.cacheDir, tag, name)
+}
+
+// lookup returns the contents of the named file for the given release tag from the cache, and whether it was there.
+func (src *releaseSource) lookup(ctx context.Context, tag, name string) ([]byte, bool, error) {
+	if src.cacheDir == "" {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(src.cacheFile(tag, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	TaskLogger(ctx).Debug("using cached file", "file", src.cacheFile(tag, name))
+	return data, true, nil
+}
+
+// store writes the named file for the given release tag into the cache, if there is one.
+func (src *releaseSource) store(tag, name string, data []byte) error {
+	if src.cacheDir == "" {
+		return nil
+	}
+	file := src.cacheFile(tag, name)
+	if err := os.MkdirAll(filepath.Dir(file), cacheDirMode); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, cacheFileMode)
+}
+
+// archiveFile is a release archive on disk.
+type archiveFile struct {
+	*os.File
+	// temporary is true for a file that was just downloaded, and false for a file in the cache.
+	temporary bool
+}
+
+// close closes the file and removes it if it's temporary.
+func (archive *archiveFile) close() error {
+	err := archive.File.Close()
+	if archive.temporary {
+		err = errors.Join(err, os.Remove(archive.Name()))
+	}
+	return err
+}
+
+// openCached opens the named file for the given release tag from the cache. It returns nil if the file isn't there.
+func (src *releaseSource) openCached(ctx context.Context, tag, name string) (*archiveFile, error) {
+	if src.cacheDir == "" {
+		return nil, nil
+	}
+	file, err := os.Open(src.cacheFile(tag, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	TaskLogger(ctx).Debug("using cached file", "file", file.Name())
+	return &archiveFile{File: file}, nil
+}
+
+// createTemp creates a temporary file for downloading the named file for the given release tag. With a cache, the
+// file goes in the cache's directory for the release, so [releaseSource.keep] can rename it into place.
+func (src *releaseSource) createTemp(tag, name string) (*archiveFile, error) {
+	dir := ""
+	if src.cacheDir != "" {
+		dir = filepath.Dir(src.cacheFile(tag, name))
+		if err := os.MkdirAll(dir, cacheDirMode); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &archiveFile{File: file, temporary: true}, nil
+}
+
+// downloadArchive streams the given asset into a temporary file, so the archive never has to fit in memory.
+func (src *releaseSource) downloadArchive(ctx context.Context, tag string, asset *releaseAsset) (*archiveFile, error) {
+	body, err := src.open(ctx, asset.BrowserDownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	archive, err := src.createTemp(tag, asset.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(archive, body); err != nil {
+		return nil, errors.Join(fmt.Errorf("Could not download %s: %w", asset.BrowserDownloadURL, err), archive.close())
+	}
+	return archive, nil
+}
+
+// fetchArchive returns the given asset from the cache, or else downloads it.
+func (src *releaseSource) fetchArchive(ctx context.Context, tag string, asset *releaseAsset) (*archiveFile, error) {
+	archive, err := src.openCached(ctx, tag, asset.Name)
+	if err != nil || archive != nil {
+		return archive, err
+	}
+	return src.downloadArchive(ctx, tag, asset)
+}
+
+// keep moves a newly downloaded archive into the cache, if there is one. Call it once the archive has been verified
+// and used, so the cache only ever holds good archives.
+func (src *releaseSource) keep(archive *archiveFile, tag, name string) error {
+	if !archive.temporary || src.cacheDir == "" {
+		return nil
+	}
+	if err := archive.File.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(archive.Name(), src.cacheFile(tag, name)); err != nil {
+		return err
+	}
+	archive.temporary = false
+	return nil
+}
+
+// cached returns the named file for the given release tag from the cache, or else fetches it from the given URL and
+// stores it in the cache.
+func (src *releaseSource) cached(ctx context.Context, tag, name, url string) ([]byte, error) {
+	data, found, err := src.lookup(ctx, tag, name)
+	if err != nil || found {
+		return data, err
+	}
+	if data, err = src.get(ctx, url); err != nil {
+		return nil, err
+	}
+	return data, src.store(tag, name, data)
+}
+
+// releaseInfo fetches golangci-lint release information for the given version, which can be "latest," a major
+// version, or a release tag. Only a release tag can come from the cache, since the others change over time.
+func (src *releaseSource) releaseInfo(ctx context.Context, version string) (info releaseInfo, err error) {
+	if majorVersion.MatchString(version) {
+		return src.latestMajorRelease(ctx, version)
+	}
+	var data []byte
+	if version == latestVersion {
+		data, err = src.get(ctx, src.baseURL()+golangciLintRepo+"/releases/latest")
+	} else {
+		data, err = src.cached(ctx, version, releaseInfoFile, src.baseURL()+golangciLintRepo+"/releases/tags/"+version)
+	}
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+// listReleases fetches information about the most recent golangci-lint releases.
+func (src *releaseSource) listReleases(ctx context.Context) (releases []releaseInfo, err error) {
+	data, err := src.get(ctx, src.baseURL()+golangciLintRepo+"/releases?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &releases)
+	return releases, err
+}
+
+// latestMajorRelease fetches release information for the newest release with the given major version, such as v1.
+// Github lists releases newest first.
+func (src *releaseSource) latestMajorRelease(ctx context.Context, major string) (releaseInfo, error) {
+	releases, err := src.listReleases(ctx)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	idx := slices.IndexFunc(releases, func(info releaseInfo) bool {
+		return !info.Prerelease && strings.HasPrefix(info.TagName, major+".")
+	})
+	if idx == -1 {
+		return releaseInfo{}, fmt.Errorf("No %s release of golangci-lint found", major)
+	}
+	return releases[idx], nil
+}
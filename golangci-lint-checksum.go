@@ -0,0 +1,175 @@
+package magehelper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// checksumsSuffix ends the name of the release asset that lists the SHA-256 checksum of every other asset, such
+	// as golangci-lint-2.1.6-checksums.txt.
+	checksumsSuffix = "-checksums.txt"
+	// checksumFileSuffix ends the name of the file that records the checksum of an installed binary.
+	checksumFileSuffix = ".sha256"
+	// checksumFileMode is the permissions for the file that records the checksum of an installed binary.
+	checksumFileMode fs.FileMode = 0o644
+)
+
+// errChecksumMismatch reports that a file's contents don't match the checksum they're supposed to have.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// streamChecksum returns the hex-encoded SHA-256 checksum of everything the given reader provides, and how many bytes
+// that was.
+func streamChecksum(r io.Reader) (string, int64, error) {
+	hash := sha256.New()
+	size, err := io.Copy(hash, r)
+	return hex.EncodeToString(hash.Sum(nil)), size, err
+}
+
+// findChecksumsAsset returns the release's checksums file.
+func findChecksumsAsset(info releaseInfo) (*releaseAsset, error) {
+	idx := slices.IndexFunc(info.Assets, func(asset releaseAsset) bool {
+		return strings.HasSuffix(asset.Name, checksumsSuffix)
+	})
+	if idx == -1 {
+		return nil, fmt.Errorf("No checksums file found in release %s", info.TagName)
+	}
+	return &info.Assets[idx], nil
+}
+
+// parseChecksums finds the checksum for the named file in the output of sha256sum.
+func parseChecksums(checksums io.Reader, name string) (string, error) {
+	scanner := bufio.NewScanner(checksums)
+	for scanner.Scan() {
+		sum, file, found := strings.Cut(scanner.Text(), "  ")
+		if found && file == name {
+			return strings.ToLower(sum), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("No checksum found for %s", name)
+}
+
+// fetchChecksum downloads the release's checksums file and returns the expected checksum of the given asset.
+func (src *releaseSource) fetchChecksum(ctx context.Context, info releaseInfo, asset *releaseAsset) (string, error) {
+	checksums, err := findChecksumsAsset(info)
+	if err != nil {
+		return "", err
+	}
+	data, err := src.cached(ctx, info.TagName, checksums.Name, checksums.BrowserDownloadURL)
+	if err != nil {
+		return "", err
+	}
+	return parseChecksums(bytes.NewReader(data), asset.Name)
+}
+
+// downloadVerified downloads the given asset, or finds it in the cache, and confirms that it has the size and checksum
+// that the release publishes for it. The returned file is ready to read from the beginning.
+func (src *releaseSource) downloadVerified(
+	ctx context.Context, info releaseInfo, asset *releaseAsset,
+) (*archiveFile, error) {
+	expected, err := src.fetchChecksum(ctx, info, asset)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := src.fetchArchive(ctx, info.TagName, asset)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyArchive(ctx, archive.File, asset, expected); err != nil {
+		return nil, errors.Join(err, archive.close())
+	}
+	return archive, nil
+}
+
+// checksumFromStart returns the checksum and size of the whole file, regardless of the file's current position.
+func checksumFromStart(file io.ReadSeeker) (string, int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	return streamChecksum(file)
+}
+
+// verifyArchive confirms that the archive has the size and checksum that the release publishes for it, and then
+// rewinds the file for extracting.
+func verifyArchive(ctx context.Context, archive *os.File, asset *releaseAsset, expected string) error {
+	actual, size, err := checksumFromStart(archive)
+	if err != nil {
+		return err
+	}
+	if asset.Size != 0 && size != int64(asset.Size) {
+		return fmt.Errorf("%s: expected %d bytes but read %d instead", asset.Name, asset.Size, size)
+	}
+	if err = verifyChecksum(ctx, asset.Name, actual, expected); err != nil {
+		return err
+	}
+	_, err = archive.Seek(0, io.SeekStart)
+	return err
+}
+
+// verifyChecksum confirms that the named file's actual checksum is the expected one.
+func verifyChecksum(ctx context.Context, name, actual, expected string) error {
+	if actual != expected {
+		return fmt.Errorf("%s: %w: want %s, got %s", name, errChecksumMismatch, expected, actual)
+	}
+	TaskLogger(ctx).Debug("verified checksum", "file", name, "sha256", actual)
+	return nil
+}
+
+// checksumFile returns the name of the file that records the checksum of the given binary.
+func checksumFile(bin string) string {
+	return bin + checksumFileSuffix
+}
+
+// fileChecksum returns the hex-encoded SHA-256 checksum of the given file.
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sum, _, err := streamChecksum(f)
+	return sum, err
+}
+
+// recordChecksum writes the checksum of the given binary beside it, so later runs can tell whether the binary has
+// changed since it was installed.
+func recordChecksum(bin string) error {
+	sum, err := fileChecksum(bin)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(checksumFile(bin), []byte(sum+"\n"), checksumFileMode)
+}
+
+// installedChecksumRecorded reports whether the given binary still matches the checksum recorded when it was
+// installed. It returns false when there's no record, such as for a binary installed by an earlier version of this
+// package, and an error when the binary doesn't match the record.
+func installedChecksumRecorded(ctx context.Context, bin string) (bool, error) {
+	recorded, err := os.ReadFile(checksumFile(bin))
+	if errors.Is(err, fs.ErrNotExist) {
+		LogExplain(ctx, "no recorded checksum for installed binary", attrBinary, bin)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	actual, err := fileChecksum(bin)
+	if err != nil {
+		return false, err
+	}
+	return true, verifyChecksum(ctx, bin, actual, strings.TrimSpace(string(recorded)))
+}
@@ -0,0 +1,27 @@
+package magehelper
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/rkennedy/magehelper/internal/golangcilint"
+)
+
+// latestVersion is the version that asks for the newest release.
+const latestVersion = golangcilint.Latest
+
+// majorVersion matches a version that names only a major version, such as v2.
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// isReleaseTag reports whether the given version names one release, as opposed to "latest" or a major version, whose
+// meaning changes as new versions are released.
+func isReleaseTag(version string) bool {
+	return version != latestVersion && !majorVersion.MatchString(version)
+}
+
+// desiredGolangciLintVersion returns the version of golangci-lint to install when the caller doesn't specify one. A
+// version pinned in go.mod wins. Otherwise, it's the latest release of the major version that the configuration file
+// is written for.
+func desiredGolangciLintVersion(ctx context.Context, modDir string) (string, error) {
+	return golangcilint.DesiredVersion(TaskLogger(ctx), modDir)
+}
@@ -0,0 +1,158 @@
+package magehelper_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+)
+
+// fakeRelease describes a golangci-lint release for the fake Github API to report.
+type fakeRelease struct {
+	tag        string
+	prerelease bool
+}
+
+var _ = Describe("InstallGolangciLint version resolution", func() {
+	var (
+		server    *httptest.Server
+		downloads []string
+		dir       string
+	)
+
+	// Releases are listed newest first, the way Github lists them.
+	releases := []fakeRelease{
+		{tag: "v2.2.0-rc.1", prerelease: true},
+		{tag: "v2.1.6"},
+		{tag: "v1.64.8"},
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("the fake golangci-lint is a shell script")
+		}
+		platform := runtime.GOOS + "-" + runtime.GOARCH
+		archive := fmt.Sprintf("golangci-lint-1.2.3-%s.tar.gz", platform)
+		content := golangciLintArchive(platform)
+		sum := sha256.Sum256(content)
+		downloads = nil
+
+		releaseJSON := func(release fakeRelease) map[string]any {
+			return map[string]any{
+				"tag_name":   release.tag,
+				"prerelease": release.prerelease,
+				"assets": []map[string]any{
+					{"name": "golangci-lint-checksums.txt", "browser_download_url": server.URL + "/dl/checksums"},
+					{"name": archive, "size": len(content), "browser_download_url": server.URL + "/dl/" + release.tag},
+				},
+			}
+		}
+
+		mux := http.NewServeMux()
+		api := "/api/repos/golangci/golangci-lint/releases"
+		mux.HandleFunc(api, func(w http.ResponseWriter, _ *http.Request) {
+			var list []map[string]any
+			for _, release := range releases {
+				list = append(list, releaseJSON(release))
+			}
+			Expect(json.NewEncoder(w).Encode(list)).To(Succeed())
+		})
+		mux.HandleFunc(api+"/latest", func(w http.ResponseWriter, _ *http.Request) {
+			Expect(json.NewEncoder(w).Encode(releaseJSON(releases[1]))).To(Succeed())
+		})
+		mux.HandleFunc(api+"/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+			for _, release := range releases {
+				if release.tag == r.PathValue("tag") {
+					Expect(json.NewEncoder(w).Encode(releaseJSON(release))).To(Succeed())
+					return
+				}
+			}
+			http.NotFound(w, r)
+		})
+		mux.HandleFunc("/dl/checksums", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), archive)
+		})
+		mux.HandleFunc("/dl/{tag}", func(w http.ResponseWriter, r *http.Request) {
+			downloads = append(downloads, r.PathValue("tag"))
+			_, _ = w.Write(content)
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+
+		// The configuration file is read from the current directory.
+		dir = GinkgoT().TempDir()
+		GinkgoT().Chdir(dir)
+	})
+
+	writeFile := func(name, content string) {
+		GinkgoHelper()
+		Expect(os.MkdirAll(filepath.Dir(name), 0o755)).To(Succeed())
+		Expect(os.WriteFile(name, []byte(content), 0o644)).To(Succeed())
+	}
+
+	// pin makes go.mod in the test's directory require the given version of the given module, replaced by a local
+	// directory so that go list needs no network.
+	pin := func(module, version string) {
+		GinkgoHelper()
+		writeFile(filepath.Join("fake", "go.mod"), "module "+module+"\n")
+		writeFile("go.mod", fmt.Sprintf(
+			"module example.com/lint\n\ngo 1.25.0\n\nrequire %s %s\n\nreplace %s => ./fake\n", module, version, module))
+	}
+
+	install := func(ctx context.Context, version string) error {
+		bin := filepath.Join(dir, "bin", "golangci-lint")
+		Expect(os.MkdirAll(filepath.Dir(bin), 0o755)).To(Succeed())
+		return magehelper.InstallGolangciLint(bin, version).APIURL(server.URL + "/api/").ModDir(dir).Run(ctx)
+	}
+
+	It("installs the latest release without a version or configuration", func(ctx context.Context) {
+		Expect(install(ctx, "")).To(Succeed())
+		Expect(downloads).To(Equal([]string{"v2.1.6"}))
+	})
+
+	It("installs the latest version 1 release for a configuration without a version", func(ctx context.Context) {
+		writeFile(".golangci.yml", "run:\n  timeout: 3m\n")
+		Expect(install(ctx, "")).To(Succeed())
+		Expect(downloads).To(Equal([]string{"v1.64.8"}))
+	})
+
+	It("installs the latest release for a version 2 configuration, skipping prereleases", func(ctx context.Context) {
+		writeFile(".golangci.json", `{"version": "2"}`)
+		Expect(install(ctx, "")).To(Succeed())
+		Expect(downloads).To(Equal([]string{"v2.1.6"}))
+	})
+
+	It("prefers the version that go.mod pins to the configuration", func(ctx context.Context) {
+		writeFile(".golangci.yml", "version: \"2\"\n")
+		pin("github.com/golangci/golangci-lint", "v1.64.8")
+		Expect(install(ctx, "")).To(Succeed())
+		Expect(downloads).To(Equal([]string{"v1.64.8"}))
+	})
+
+	It("reads the pinned version of the version 2 module", func(ctx context.Context) {
+		pin("github.com/golangci/golangci-lint/v2", "v2.1.6")
+		Expect(install(ctx, "")).To(Succeed())
+		Expect(downloads).To(Equal([]string{"v2.1.6"}))
+	})
+
+	It("installs the latest release of a major version", func(ctx context.Context) {
+		Expect(install(ctx, "v1")).To(Succeed())
+		Expect(downloads).To(Equal([]string{"v1.64.8"}))
+	})
+
+	It("reports a major version without releases", func(ctx context.Context) {
+		Expect(install(ctx, "v3")).To(MatchError(ContainSubstring("No v3 release")))
+		Expect(downloads).To(BeEmpty())
+	})
+})
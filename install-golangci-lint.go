@@ -3,10 +3,8 @@ package magehelper
 import (
 	"archive/tar"
 	"archive/zip"
-	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,13 +12,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"slices"
 	"strings"
 
-	"github.com/magefile/mage/mg"
-	"github.com/magefile/mage/sh"
+	"github.com/rkennedy/magehelper/internal/golangcilint"
 )
 
 // releaseAsset is a subset of the asset information reported in a Github release.
@@ -33,8 +29,28 @@ type releaseAsset struct {
 
 // releaseInfo is a subset of the information reported in a Github release.
 type releaseInfo struct {
-	TagName string         `json:"tag_name"`
-	Assets  []releaseAsset `json:"assets"`
+	TagName    string         `json:"tag_name"`
+	Prerelease bool           `json:"prerelease"`
+	Assets     []releaseAsset `json:"assets"`
+}
+
+// Extensions of the archive formats that golangci-lint releases use. Releases also include system packages such as
+// .deb files, so assets are chosen by extension rather than by content type, which Github doesn't report consistently.
+const (
+	tarGzExtension = ".tar.gz"
+	zipExtension   = ".zip"
+)
+
+// archiveExtension returns the archive extension of the given asset name, or the empty string if the asset isn't an
+// archive that the installer can unpack.
+func archiveExtension(name string) string {
+	idx := slices.IndexFunc([]string{tarGzExtension, zipExtension}, func(ext string) bool {
+		return strings.HasSuffix(name, ext)
+	})
+	if idx == -1 {
+		return ""
+	}
+	return []string{tarGzExtension, zipExtension}[idx]
 }
 
 func findTarFile(reader *tar.Reader, targetFileName string) (io.Reader, fs.FileMode, error) {
@@ -50,73 +66,94 @@ func findTarFile(reader *tar.Reader, targetFileName string) (io.Reader, fs.FileM
 }
 
 // Given a source stream pointing to the golangci-lint binary we want to extract, write it to the desired location and
-// apply the given file permissions.
-func writeArchivedFile(source io.Reader, dest string, mode fs.FileMode) error {
-	// The file we'll be writing to.
-	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+// apply the given file permissions. The binary goes to a temporary file beside the destination first, and then it's
+// renamed into place, so an interrupted install never leaves a partial binary behind.
+func writeArchivedFile(source io.Reader, dest string, mode fs.FileMode) (err error) {
+	out, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("Could not open target for writing: %w", err)
 	}
-	defer out.Close()
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, os.Remove(out.Name()))
+		}
+	}()
 
 	_, err = io.Copy(out, source)
-	if err != nil {
+	if err = errors.Join(err, out.Chmod(mode), out.Close()); err != nil {
 		return fmt.Errorf("Could not write file to disk: %w", err)
 	}
-
-	return nil
+	return os.Rename(out.Name(), dest)
 }
 
-// Receive a zip file and return the same file, plus its file size. We do it this way to keep function lengths short
-// like our linter wants.
-func withZipSize(f fs.File, err error) (io.Reader, fs.FileMode, error) {
-	if err != nil {
-		return nil, 0, err
+// Find the target file within the zip file, which may be in a subdirectory.
+func findZipFile(reader *zip.Reader, targetFileName string) (*zip.File, error) {
+	idx := slices.IndexFunc(reader.File, func(f *zip.File) bool {
+		return filepath.Base(f.Name) == targetFileName
+	})
+	if idx == -1 {
+		return nil, fmt.Errorf("Could not find %s in zip file", targetFileName)
 	}
-	info, err := f.Stat()
+	return reader.File[idx], nil
+}
+
+// Extract the golangci-lint binary from the given gzipped tarball.
+func extractTarGz(archive io.Reader, bin string) error {
+	unzipper, err := gzip.NewReader(archive)
 	if err != nil {
-		return nil, 0, err
+		return fmt.Errorf("Could not open payload as gzip: %w", err)
 	}
-	header, err := zip.FileInfoHeader(info)
+	defer unzipper.Close()
+
+	source, mode, err := findTarFile(tar.NewReader(unzipper), golangciLintBinary())
 	if err != nil {
-		return nil, 0, err
+		return fmt.Errorf("Could not find target file in tar stream: %w", err)
 	}
-	return f, header.Mode(), nil
+	return writeArchivedFile(source, bin, mode)
 }
 
-// Find the target file within the zip file represented by the body.
-func openZip(size int, body io.Reader, targetFileName string) (io.Reader, fs.FileMode, error) {
-	// zip.NewReader requires a ReaderAt, which http doesn't provide. We'll store the resource in memory and
-	// then open the zip file from the in-memory buffer.
-	zipBuffer := make([]byte, size)
-
-	written, err := io.Copy(bytes.NewBuffer(zipBuffer), body)
+// Find the target file within the given zip file. Zip files keep their directory at the end, so reading one requires
+// random access to the whole file, not just a stream.
+func openZip(archive *os.File, targetFileName string) (*zip.File, error) {
+	info, err := archive.Stat()
 	if err != nil {
-		return nil, 0, fmt.Errorf("Could not store zip file: %w", err)
+		return nil, err
 	}
-	if int(written) != size {
-		return nil, 0, fmt.Errorf("Expected %d bytes but read %d instead", size, written)
+	unzipper, err := zip.NewReader(archive, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("Could not open zip file: %w", err)
 	}
+	return findZipFile(unzipper, targetFileName)
+}
 
-	unzipper, err := zip.NewReader(bytes.NewReader(zipBuffer), 0)
+// Extract the golangci-lint binary from the given zip file.
+func extractZip(archive *os.File, bin string) error {
+	file, err := openZip(archive, golangciLintBinary())
+	if err != nil {
+		return err
+	}
+	source, err := file.Open()
 	if err != nil {
-		return nil, 0, fmt.Errorf("Could not open zip file: %w", err)
+		return err
 	}
-	return withZipSize(unzipper.Open(targetFileName))
+	defer source.Close()
+
+	return writeArchivedFile(source, bin, file.Mode())
 }
 
 // In the releaseInfo's list of assets, find the first tarball or zip file that has a GOOS and GOARCH matching the
-// current runtime environment.
-func findAsset(info releaseInfo) (*releaseAsset, error) {
-	targetSubstring := fmt.Sprintf("-%s-%s.", runtime.GOOS, runtime.GOARCH)
+// current runtime environment. Both major versions name their archives the same way, such as
+// golangci-lint-2.1.6-linux-amd64.tar.gz.
+func findAsset(ctx context.Context, info releaseInfo) (*releaseAsset, error) {
+	platform := fmt.Sprintf("-%s-%s", runtime.GOOS, runtime.GOARCH)
 	idx := slices.IndexFunc(info.Assets, func(asset releaseAsset) bool {
-		return (asset.ContentType == "application/gzip" || asset.ContentType == "application/zip") &&
-			strings.Contains(asset.Name, targetSubstring)
+		ext := archiveExtension(asset.Name)
+		return ext != "" && strings.HasSuffix(strings.TrimSuffix(asset.Name, ext), platform)
 	})
 	if idx == -1 {
 		return nil, fmt.Errorf("No binary found for %s/%s", runtime.GOOS, runtime.GOARCH)
 	}
-	LogV("Found release for %s\n", info.Assets[idx].Name)
+	TaskLogger(ctx).Debug("found release asset", "asset", info.Assets[idx].Name, attrVersion, info.TagName)
 	return &info.Assets[idx], nil
 }
 
@@ -130,141 +167,189 @@ func golangciLintBinary() string {
 	return targetFileName
 }
 
-// Given an HTTP body, use the asset's content type to open the body as a tarball or zip file and extract the
+// Given a downloaded archive, use the asset's extension to open it as a tarball or zip file and extract the
 // golangci-lint binary to the desired binary location.
-//
-//revive:disable-next-line:function-length 14 instructions is fine here.
-func writeDownloadedFile(body io.Reader, asset *releaseAsset, bin string) (err error) {
-	var source io.Reader
-	var mode fs.FileMode
-	switch asset.ContentType {
-	case "application/gzip":
-		unzipper, err := gzip.NewReader(body)
-		if err != nil {
-			return fmt.Errorf("Could not open payload as gzip: %w", err)
-		}
-		defer unzipper.Close()
-
-		source, mode, err = findTarFile(tar.NewReader(unzipper), golangciLintBinary())
-		if err != nil {
-			return fmt.Errorf("Could not find target file in tar stream: %w", err)
-		}
-	case "application/zip":
-		source, mode, err = openZip(asset.Size, body, golangciLintBinary())
-		if err != nil {
-			return err
-		}
+func writeDownloadedFile(archive *os.File, asset *releaseAsset, bin string) error {
+	switch archiveExtension(asset.Name) {
+	case tarGzExtension:
+		return extractTarGz(archive, bin)
+	case zipExtension:
+		return extractZip(archive, bin)
 	default:
-		return fmt.Errorf("Unknown asset content-type %s", asset.ContentType)
-	}
-	return writeArchivedFile(source, bin, mode)
-}
-
-func getURL(ctx context.Context, url string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+		return fmt.Errorf("Unknown asset type %s", asset.Name)
 	}
-	return http.DefaultClient.Do(req)
 }
 
 // Download the distribution package for the current platform (GOOS and GOARCH), unpack it, and store it at the given
 // binary location.
-func fetchAndWriteGolangciLint(ctx context.Context, info releaseInfo, bin string) error {
-	asset, err := findAsset(info)
+func (src *releaseSource) fetchAndWriteGolangciLint(ctx context.Context, info releaseInfo, bin string) error {
+	asset, err := findAsset(ctx, info)
 	if err != nil {
 		return err
 	}
+	if DryRun() {
+		LogDryRun(ctx, "would download", "url", asset.BrowserDownloadURL, "file", golangciLintBinary(), attrBinary, bin)
+		markPending(bin)
+		return nil
+	}
+	return src.downloadAndWrite(ctx, info, asset, bin)
+}
 
-	resp, err := getURL(ctx, asset.BrowserDownloadURL)
+// Download the given asset, verify it against the release's checksums, unpack it, and store it at the given binary
+// location. Nothing is extracted from an archive that fails verification. After writing the binary, record its
+// checksum so later runs can detect changes to it.
+func (src *releaseSource) downloadAndWrite(
+	ctx context.Context, info releaseInfo, asset *releaseAsset, bin string,
+) error {
+	archive, err := src.downloadVerified(ctx, info, asset)
 	if err != nil {
-		return fmt.Errorf("Could not download file: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	defer archive.close()
 
-	return writeDownloadedFile(resp.Body, asset, bin)
+	if err = writeDownloadedFile(archive.File, asset, bin); err != nil {
+		return err
+	}
+	if err = recordChecksum(bin); err != nil {
+		return err
+	}
+	return src.keep(archive, info.TagName, asset.Name)
 }
 
-type installGolangciLintTask struct {
+// InstallGolangciLintTask is a Mage task that installs golangci-lint from its Github releases.
+type InstallGolangciLintTask struct {
 	golangciLintBin string
 	version         string
+	modDir          string
+	source          releaseSource
 }
 
-var _ mg.Fn = &installGolangciLintTask{}
+var _ InstallTask = &InstallGolangciLintTask{}
 
-func (fn installGolangciLintTask) ID() string {
+// ID implements [mg.Fn].
+func (fn *InstallGolangciLintTask) ID() string {
 	return fmt.Sprintf("magehelper install %s %s", fn.golangciLintBin, fn.version)
 }
 
-func (fn installGolangciLintTask) Name() string {
-	return fmt.Sprintf("Install golangci-lint %s", fn.version)
+// Name implements [mg.Fn].
+func (fn *InstallGolangciLintTask) Name() string {
+	return strings.TrimSpace(fmt.Sprintf("Install golangci-lint %s", fn.version))
 }
 
-func (fn installGolangciLintTask) Run(ctx context.Context) error {
-	fileVersion, err := golangcilintVersion(fn.golangciLintBin)
-	// Mage doesn't use %w to wrap errors. Every error is just a string, so Is(ErrNotExist) doesn't work.
-	if err != nil && !errors.Is(err, fs.ErrNotExist) && !strings.Contains(err.Error(), "no such file or directory") {
+// Run implements [mg.Fn].
+func (fn *InstallGolangciLintTask) Run(ctx context.Context) error {
+	return RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *InstallGolangciLintTask) execute(ctx context.Context) error {
+	fileVersion, err := fn.installedVersion(ctx)
+	if err != nil {
 		return err
 	}
 
-	info, err := getReleaseInfo(ctx, fn.version)
+	version, err := fn.wantedVersion(ctx)
 	if err != nil {
 		return err
 	}
 
-	if fileVersion == info.TagName {
-		LogV("Command is up to date; file %s, tag %s.\n", fileVersion, info.TagName)
-		return nil
+	info, err := fn.wantedRelease(ctx, fileVersion, version)
+	if err != nil || info == nil {
+		return err
 	}
-	return fetchAndWriteGolangciLint(ctx, info, fn.golangciLintBin)
+	return fn.source.fetchAndWriteGolangciLint(ctx, *info, fn.golangciLintBin)
 }
 
-// InstallGolangciLint creates a dependency on the given version of golangci-lint to be installed at the given binary
-// location. Pass this to [mg.Deps] or [mg.CtxDeps]. If another version is already installed, then it is overwritten
-// with the requested version. Fetching the requested version requires access to github.com.
-func InstallGolangciLint(bin string, version string) mg.Fn {
-	return &installGolangciLintTask{bin, version}
+// wantedRelease returns information about the release to install, or nil if the installed version is already the
+// wanted one. A release tag is compared with the installed version before fetching anything, so an up-to-date binary
+// needs no request to the Github API. Other versions, such as "latest," have to be looked up to learn which release
+// they mean.
+func (fn *InstallGolangciLintTask) wantedRelease(ctx context.Context, have, version string) (*releaseInfo, error) {
+	if isReleaseTag(version) && versionCurrent(ctx, fn.golangciLintBin, have, version) {
+		return nil, nil
+	}
+	info, err := fn.source.releaseInfo(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	if !isReleaseTag(version) && versionCurrent(ctx, fn.golangciLintBin, have, info.TagName) {
+		return nil, nil
+	}
+	return &info, nil
 }
 
-// Get the version of the program at the current location.
-func golangcilintVersion(bin string) (string, error) {
-	output, err := sh.Output(bin, "--version")
-	if err != nil {
-		return "", err
+// installedVersion returns the version of the installed binary, or the empty string if it needs to be installed
+// because it's missing or has no recorded checksum. The binary only runs once it matches the checksum recorded when
+// it was installed. A binary that doesn't match has been tampered with, so that's an error instead of a reason to run
+// it.
+func (fn *InstallGolangciLintTask) installedVersion(ctx context.Context) (string, error) {
+	if _, err := os.Stat(fn.golangciLintBin); errors.Is(err, fs.ErrNotExist) {
+		LogExplain(ctx, "binary is not installed", attrBinary, fn.golangciLintBin)
+		return "", nil
 	}
-	// golangci-lint has version 1.57.2 built with go1.22.1 from 77a8601a on 2024-03-28T19:01:11Z
-	re, err := regexp.Compile(`golangci-lint has version ([.0-9]+) `)
-	if err != nil {
+	verified, err := installedChecksumRecorded(ctx, fn.golangciLintBin)
+	if err != nil || !verified {
 		return "", err
 	}
-	matches := re.FindStringSubmatch(output)
-	if len(matches) <= 1 {
-		return "", fmt.Errorf("Could not find version in %s", output)
+	return golangcilint.InstalledVersion(fn.golangciLintBin)
+}
+
+// wantedVersion returns the version to install. When the task has no version, it uses the version that go.mod pins or
+// the latest release for the configuration file's major version.
+func (fn *InstallGolangciLintTask) wantedVersion(ctx context.Context) (string, error) {
+	if fn.version != "" {
+		return fn.version, nil
 	}
-	return "v" + matches[1], nil
+	return desiredGolangciLintVersion(ctx, fn.modDir)
 }
 
-func decodeBody(body io.Reader) (releaseInfo, error) {
-	dec := json.NewDecoder(body)
-	var info releaseInfo
-	err := dec.Decode(&info)
-	return info, err
+// ModDir sets the directory holding the go.mod file that might pin the version of golangci-lint. It only matters when
+// the version is empty.
+func (fn *InstallGolangciLintTask) ModDir(dir string) InstallTask {
+	fn.modDir = dir
+	return fn
 }
 
-// Fetch golangci-lint release information from Github for the given version, which can be either "latest" or a release
-// tag.
-func getReleaseInfo(ctx context.Context, version string) (releaseInfo, error) {
-	// Download release info for requested version. (If the requested version is "latest" then that will also tell
-	// us the actual version, so we can compare with the current file's version.)
-	url := "https://api.github.com/repos/golangci/golangci-lint/releases/latest"
-	if version != "latest" {
-		url = fmt.Sprintf("https://api.github.com/repos/golangci/golangci-lint/releases/tags/%s", version)
-	}
-	resp, err := getURL(ctx, url)
-	if err != nil {
-		return releaseInfo{}, err
-	}
-	defer resp.Body.Close()
+// APIURL sets the base URL of the Github API that provides release information, such as the address of an internal
+// mirror or of a test server. The default comes from the GITHUB_API_URL environment variable, which Github Actions
+// sets, or else it's https://api.github.com. APIURL returns the InstallGolangciLintTask.
+func (fn *InstallGolangciLintTask) APIURL(url string) *InstallGolangciLintTask {
+	fn.source.apiURL = url
+	return fn
+}
+
+// HTTPClient sets the client for fetching release information and downloads. The default is [http.DefaultClient].
+// HTTPClient returns the InstallGolangciLintTask.
+func (fn *InstallGolangciLintTask) HTTPClient(client *http.Client) *InstallGolangciLintTask {
+	fn.source.client = client
+	return fn
+}
+
+// CacheDir sets a directory for keeping release information, checksums, and archives between runs. Files are kept in
+// a subdirectory for each release, and archive names include the platform, so one cache can serve several versions
+// and platforms. Once a release is cached, installing it again needs no network, provided the version is a release
+// tag, which includes a version pinned in go.mod. The default is not to cache anything. CacheDir returns the
+// InstallGolangciLintTask.
+func (fn *InstallGolangciLintTask) CacheDir(dir string) *InstallGolangciLintTask {
+	fn.source.cacheDir = dir
+	return fn
+}
 
-	return decodeBody(resp.Body)
+// InstallGolangciLint creates a dependency on the given version of golangci-lint to be installed at the given binary
+// location. Pass this to [mg.Deps] or [mg.CtxDeps]. If another version is already installed, then it is overwritten
+// with the requested version. Fetching the requested version requires access to github.com, or to the server that
+// [InstallGolangciLintTask.APIURL] names, unless [InstallGolangciLintTask.CacheDir] already holds it. A release tag
+// that's already installed needs no access at all. When the GITHUB_TOKEN environment variable is set, the task uses it
+// to authenticate its API requests, which raises Github's rate limit.
+//
+// The task verifies the downloaded archive against the SHA-256 checksums published with the release and fails if
+// they don't match. It records the installed binary's checksum in a file beside it with a .sha256 extension, and on
+// later runs, it fails without running the binary if the binary no longer matches that checksum.
+//
+// The version may be a release tag such as v2.1.6, a major version such as v2 for the latest release with that major
+// version, or "latest." If it's empty, the task uses the version of github.com/golangci/golangci-lint/v2 or
+// github.com/golangci/golangci-lint that go.mod requires, such as for a tool directive. Failing that, it reads
+// .golangci.yml, .golangci.yaml, or .golangci.json from the current directory and uses the latest release of the major
+// version that the configuration is written for.
+func InstallGolangciLint(bin string, version string) *InstallGolangciLintTask {
+	return &InstallGolangciLintTask{golangciLintBin: bin, version: version}
 }
@@ -0,0 +1,207 @@
+package magehelper_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+)
+
+// fakeGolangciLintMode is the permissions of the fake golangci-lint in release archives.
+const fakeGolangciLintMode fs.FileMode = 0o755
+
+const fakeGolangciLint = `#!/bin/sh
+echo "golangci-lint has version v1.2.3 built with go1.25.0 from 0123abcd on 2025-01-01T00:00:00Z"
+`
+
+// golangciLintArchive returns a gzipped tarball holding a script that reports the version, in a subdirectory the way
+// real releases do.
+func golangciLintArchive(platform string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	Expect(tw.WriteHeader(&tar.Header{
+		Name: fmt.Sprintf("golangci-lint-1.2.3-%s/golangci-lint", platform),
+		Mode: int64(fakeGolangciLintMode),
+		Size: int64(len(fakeGolangciLint)),
+	})).To(Succeed())
+	_, err := tw.Write([]byte(fakeGolangciLint))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+// golangciLintZip returns a zip file with the same contents as [golangciLintArchive].
+func golangciLintZip(platform string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	header := &zip.FileHeader{Name: fmt.Sprintf("golangci-lint-1.2.3-%s/golangci-lint", platform)}
+	header.SetMode(fakeGolangciLintMode)
+	w, err := zw.CreateHeader(header)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = w.Write([]byte(fakeGolangciLint))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(zw.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("InstallGolangciLint", func() {
+	var (
+		server    *httptest.Server
+		requests  atomic.Int32
+		auth      []string
+		checksums []byte
+		archive   string
+		content   []byte
+		platform  string
+		bin       string
+		cache     string
+		// truncated is how many bytes at the end of the archive the server leaves out.
+		truncated int
+	)
+
+	serveArchive := func(name string, data []byte) {
+		archive, content = name, data
+		sum := sha256.Sum256(content)
+		checksums = []byte(hex.EncodeToString(sum[:]) + "  " + archive + "\n")
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("the fake golangci-lint is a shell script")
+		}
+		platform = runtime.GOOS + "-" + runtime.GOARCH
+		serveArchive(fmt.Sprintf("golangci-lint-1.2.3-%s.tar.gz", platform), golangciLintArchive(platform))
+		requests.Store(0)
+		auth = nil
+		truncated = 0
+
+		mux := http.NewServeMux()
+		release := "/api/repos/golangci/golangci-lint/releases/tags/v1.2.3"
+		mux.HandleFunc(release, func(w http.ResponseWriter, r *http.Request) {
+			auth = append(auth, r.Header.Get("Authorization"))
+			Expect(json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v1.2.3",
+				"assets": []map[string]any{
+					{"name": "golangci-lint-1.2.3-checksums.txt", "browser_download_url": server.URL + "/dl/checksums"},
+					{"name": "golangci-lint-1.2.3-amd64.deb", "browser_download_url": server.URL + "/dl/deb"},
+					{"name": archive, "size": len(content), "browser_download_url": server.URL + "/dl/archive"},
+				},
+			})).To(Succeed())
+		})
+		mux.HandleFunc("/dl/checksums", func(w http.ResponseWriter, r *http.Request) {
+			auth = append(auth, r.Header.Get("Authorization"))
+			_, _ = w.Write(checksums)
+		})
+		mux.HandleFunc("/dl/archive", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(content[:len(content)-truncated])
+		})
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			mux.ServeHTTP(w, r)
+		}))
+		DeferCleanup(server.Close)
+
+		dir := GinkgoT().TempDir()
+		bin = filepath.Join(dir, "bin", "golangci-lint")
+		Expect(os.MkdirAll(filepath.Dir(bin), 0o755)).To(Succeed())
+		cache = filepath.Join(dir, "cache")
+	})
+
+	install := func(ctx context.Context) error {
+		return magehelper.InstallGolangciLint(bin, "v1.2.3").APIURL(server.URL + "/api/").CacheDir(cache).Run(ctx)
+	}
+
+	It("installs a verified binary", func(ctx context.Context) {
+		Expect(install(ctx)).To(Succeed())
+		Expect(bin).To(BeAnExistingFile())
+		Expect(bin + ".sha256").To(BeAnExistingFile())
+	})
+
+	It("installs from a zip file", func(ctx context.Context) {
+		serveArchive(fmt.Sprintf("golangci-lint-1.2.3-%s.zip", platform), golangciLintZip(platform))
+		Expect(install(ctx)).To(Succeed())
+		Expect(bin).To(BeAnExistingFile())
+		Expect(filepath.Join(cache, "v1.2.3", archive)).To(BeAnExistingFile())
+	})
+
+	It("authenticates API requests only", func(ctx context.Context) {
+		GinkgoT().Setenv(magehelper.GithubTokenEnv, "secret")
+		Expect(install(ctx)).To(Succeed())
+		Expect(auth).To(Equal([]string{"Bearer secret", ""}))
+	})
+
+	It("rejects an archive that doesn't match its checksum", func(ctx context.Context) {
+		checksums = bytes.Repeat([]byte("0"), sha256.Size*2)
+		checksums = append(checksums, "  "+archive+"\n"...)
+		Expect(install(ctx)).To(MatchError(ContainSubstring("checksum mismatch")))
+		Expect(bin).NotTo(BeAnExistingFile())
+	})
+
+	It("rejects a truncated download", func(ctx context.Context) {
+		truncated = 10
+		Expect(install(ctx)).To(MatchError(ContainSubstring(
+			fmt.Sprintf("expected %d bytes but read %d", len(content), len(content)-truncated))))
+		Expect(bin).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(cache, "v1.2.3", archive)).NotTo(BeAnExistingFile())
+		Expect(filepath.Glob(filepath.Join(cache, "v1.2.3", "*.tmp"))).To(BeEmpty())
+	})
+
+	It("reinstalls from the cache without the network", func(ctx context.Context) {
+		Expect(install(ctx)).To(Succeed())
+		Expect(os.Remove(bin)).To(Succeed())
+		requests.Store(0)
+		Expect(install(ctx)).To(Succeed())
+		Expect(bin).To(BeAnExistingFile())
+		Expect(requests.Load()).To(BeZero())
+	})
+
+	It("checks an installed release without the network", func(ctx context.Context) {
+		task := func() *magehelper.InstallGolangciLintTask {
+			return magehelper.InstallGolangciLint(bin, "v1.2.3").APIURL(server.URL + "/api/")
+		}
+		Expect(task().Run(ctx)).To(Succeed())
+		requests.Store(0)
+		Expect(task().Run(ctx)).To(Succeed())
+		Expect(requests.Load()).To(BeZero())
+	})
+
+	It("records the installed binary's checksum", func(ctx context.Context) {
+		Expect(install(ctx)).To(Succeed())
+		sum := sha256.Sum256([]byte(fakeGolangciLint))
+		Expect(os.ReadFile(bin + ".sha256")).To(Equal([]byte(hex.EncodeToString(sum[:]) + "\n")))
+	})
+
+	It("detects a modified binary without running it", func(ctx context.Context) {
+		Expect(install(ctx)).To(Succeed())
+		marker := filepath.Join(filepath.Dir(bin), "ran")
+		Expect(os.WriteFile(bin, []byte(fakeGolangciLint+"touch "+marker+"\n"), 0o755)).To(Succeed())
+		Expect(install(ctx)).To(MatchError(ContainSubstring("checksum mismatch")))
+		Expect(marker).NotTo(BeAnExistingFile())
+	})
+
+	It("reinstalls a binary without a recorded checksum", func(ctx context.Context) {
+		Expect(os.WriteFile(bin, []byte(fakeGolangciLint+"# installed elsewhere\n"), 0o755)).To(Succeed())
+		Expect(install(ctx)).To(Succeed())
+		Expect(os.ReadFile(bin)).To(Equal([]byte(fakeGolangciLint)))
+		Expect(bin + ".sha256").To(BeAnExistingFile())
+	})
+})
@@ -9,27 +9,28 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/magefile/mage/mg"
-	"github.com/magefile/mage/sh"
 )
 
-func currentFileVersion(bin string) (string, error) {
+func currentFileVersion(ctx context.Context, bin string) (string, error) {
 	binInfo, err := buildinfo.ReadFile(bin)
 	if err != nil {
 		// Either file doesn't exist or we couldn't read it. Either way, we want to install it.
-		LogV("%v\n", err)
-		if err := sh.Rm(bin); err != nil {
+		TaskLogger(ctx).Debug("cannot read build info", attrBinary, bin, attrError, err)
+		LogExplain(ctx, "cannot read installed version", attrBinary, bin, attrError, err)
+		if err := Rm(ctx, bin); err != nil {
 			return "", err
 		}
 		return "", err
 	}
-	LogV("%s version %s\n", bin, binInfo.Main.Version)
+	TaskLogger(ctx).Debug("found installed binary", attrBinary, bin, attrVersion, binInfo.Main.Version)
 	return binInfo.Main.Version, nil
 }
 
-func configuredModuleVersion(thisDir, module string) (string, error) {
+func configuredModuleVersion(ctx context.Context, thisDir, module string) (string, error) {
 	c := exec.Command(mg.GoCmd(),
 		"list",
 		"-f", "{{.Module.Version}}",
@@ -43,22 +44,35 @@ func configuredModuleVersion(thisDir, module string) (string, error) {
 		return "", err
 	}
 	listOutput := strings.TrimSuffix(string(output), "\n")
-	LogV("module %s version %s\n", module, listOutput)
+	TaskLogger(ctx).Debug("found configured module", attrModule, module, attrVersion, listOutput)
 	return listOutput, nil
 }
 
-func installModule(thisDir, module, bin string) error {
+func installModule(ctx context.Context, thisDir, module, bin string) error {
 	gobin, err := filepath.Abs(filepath.Dir(bin))
 	if err != nil {
 		return err
 	}
-	LogV("Installing %s to %s\n", module, gobin)
+	TaskLogger(ctx).Debug("installing module", attrModule, module, "gobin", gobin)
 	c := exec.Command(mg.GoCmd(), "install", module)
 	c.Env = append(os.Environ(), "GOBIN="+gobin)
 	c.Dir = thisDir
-	c.Stdout, c.Stderr = os.Stdout, os.Stderr
-	c.Stdin = os.Stdin
-	return c.Run()
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	markPending(bin)
+	return RunCmd(ctx, c)
+}
+
+// versionCurrent reports whether the installed version of a tool is the wanted version. It logs the decision in dry-run
+// mode, and in explain mode, it says when the versions differ.
+func versionCurrent(ctx context.Context, bin, have, want string) bool {
+	if have == want {
+		TaskLogger(ctx).Debug("command is up to date", attrBinary, bin, attrVersion, want)
+		LogDryRun(ctx, "binary is up to date", attrBinary, bin, attrVersion, want)
+		return true
+	}
+	LogDryRun(ctx, "binary is out of date", attrBinary, bin, "have", have, "want", want)
+	LogExplain(ctx, "installed version differs from wanted version", attrBinary, bin, "have", have, "want", want)
+	return false
 }
 
 // InstallTask is an interface that extends [mg.Fn] for tasks that install tools based on module versions recorded in
@@ -85,22 +99,25 @@ func (tool *regularInstallTask) Name() string {
 	return fmt.Sprintf("Install %s", tool.bin)
 }
 
-func (tool *regularInstallTask) Run(context.Context) error {
-	fileVersion, err := currentFileVersion(tool.bin)
+func (tool *regularInstallTask) Run(ctx context.Context) error {
+	return RunTask(ctx, tool, tool.execute)
+}
+
+func (tool *regularInstallTask) execute(ctx context.Context) error {
+	fileVersion, err := currentFileVersion(ctx, tool.bin)
 	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 
-	moduleVersion, err := configuredModuleVersion(tool.modDir, tool.module)
+	moduleVersion, err := configuredModuleVersion(ctx, tool.modDir, tool.module)
 	if err != nil {
 		return err
 	}
 
-	if fileVersion == moduleVersion {
-		LogV("Command %s is up to date.\n", tool.bin)
+	if versionCurrent(ctx, tool.bin, fileVersion, moduleVersion) {
 		return nil
 	}
-	return installModule(tool.modDir, tool.module, tool.bin)
+	return installModule(ctx, tool.modDir, tool.module, tool.bin)
 }
 
 // ModDir instructs the task where to find the go.mod file that governs the Magefile. If the magefiles are in the same
@@ -112,7 +129,12 @@ func (tool *regularInstallTask) ModDir(dir string) InstallTask {
 	return tool
 }
 
-const golangciLintImport = "github.com/golangci/golangci-lint/cmd/golangci-lint"
+// golangciLintImports lists the import paths of golangci-lint's command for each major version. The golangci-lint
+// project doesn't support installing with go install, so use [InstallGolangciLint] instead.
+var golangciLintImports = []string{
+	"github.com/golangci/golangci-lint/cmd/golangci-lint",
+	"github.com/golangci/golangci-lint/v2/cmd/golangci-lint",
+}
 
 // Install returns a [mg.Fn] object suitable for using with [mg.Deps] and similar. When resolved, the object will
 // install the given module to the given binary location, using the version of the module declared in go.mod. If the
@@ -120,7 +142,7 @@ const golangciLintImport = "github.com/golangci/golangci-lint/cmd/golangci-lint"
 // tool version is not the same as the go.mod for the project being built, then call ModDir to specify
 // what directory to find the right go.mod file in.
 func Install(bin, module string) InstallTask {
-	if module == golangciLintImport {
+	if slices.Contains(golangciLintImports, module) {
 		task := errorInstallTask(module)
 		return &task
 	}
@@ -0,0 +1,50 @@
+package golangcilint
+
+import (
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles lists the configuration files that golangci-lint looks for in the current directory, in the order it
+// prefers them. TOML configuration isn't listed because magehelper can't read it.
+var configFiles = []string{".golangci.yml", ".golangci.yaml", ".golangci.json"}
+
+// RunConfig is the run section of a golangci-lint configuration file.
+type RunConfig struct {
+	// Timeout is how long golangci-lint may run, in the format of [time.ParseDuration].
+	Timeout string `yaml:"timeout"`
+}
+
+// Config holds the parts of a golangci-lint configuration file that magehelper's tasks use.
+type Config struct {
+	// Version is the version of the configuration format. Version 2 configuration files set it, and earlier ones
+	// don't.
+	Version string    `yaml:"version"`
+	Run     RunConfig `yaml:"run"`
+}
+
+// ConfigFile returns the golangci-lint configuration file in the current directory, or the empty string if there
+// isn't one. It looks for .golangci.yml, .golangci.yaml, and .golangci.json, in the order that golangci-lint prefers
+// them.
+func ConfigFile() string {
+	idx := slices.IndexFunc(configFiles, func(file string) bool {
+		_, err := os.Stat(file)
+		return err == nil
+	})
+	if idx == -1 {
+		return ""
+	}
+	return configFiles[idx]
+}
+
+// ReadConfig reads the given golangci-lint configuration file. JSON is a subset of YAML, so it reads both formats.
+func ReadConfig(file string) (config Config, err error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+	err = yaml.Unmarshal(content, &config)
+	return config, err
+}
@@ -0,0 +1,3 @@
+// Package golangcilint reads golangci-lint's configuration and version for the installer in magehelper and for the
+// lint task in magehelper/tools.
+package golangcilint
@@ -0,0 +1,13 @@
+package golangcilint_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGolangciLintSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Magehelper golangci-lint")
+}
@@ -0,0 +1,85 @@
+package golangcilint
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/magefile/mage/mg"
+	"github.com/magefile/mage/sh"
+)
+
+// Latest is the version that asks for the newest release.
+const Latest = "latest"
+
+// modules lists the modules that provide golangci-lint, newest major version first. A project can pin a version by
+// requiring one of them in go.mod, such as with a tool directive.
+var modules = []string{
+	"github.com/golangci/golangci-lint/v2",
+	"github.com/golangci/golangci-lint",
+}
+
+// versionOutput matches the version in the output of golangci-lint --version for both major versions:
+//
+//	golangci-lint has version 1.57.2 built with go1.22.1 from 77a8601a on 2024-03-28T19:01:11Z
+//	golangci-lint has version v2.1.6 built with go1.24.2 from eabc2638 on 2025-05-04T15:41:19Z
+var versionOutput = regexp.MustCompile(`golangci-lint has version v?([.0-9]+) `)
+
+// InstalledVersion runs the golangci-lint at the given location and returns the version it reports, such as v2.1.6. It
+// runs the binary without verifying it, so call it only after the installer has checked the binary.
+func InstalledVersion(bin string) (string, error) {
+	output, err := sh.Output(bin, "--version")
+	if err != nil {
+		return "", err
+	}
+	matches := versionOutput.FindStringSubmatch(output)
+	if len(matches) <= 1 {
+		return "", fmt.Errorf("Could not find version in %s", output)
+	}
+	return "v" + matches[1], nil
+}
+
+// pinnedModuleVersion returns the version of the given module that go.mod in the given directory requires.
+func pinnedModuleVersion(logger *slog.Logger, dir, module string) (string, error) {
+	c := exec.Command(mg.GoCmd(), "list", "-m", "-f", "{{.Version}}", module)
+	c.Dir = dir
+	output, err := c.Output()
+	if err != nil {
+		logger.Debug("module not required", "module", module, "error", err)
+		return "", err
+	}
+	version := strings.TrimSpace(string(output))
+	logger.Debug("found pinned module", "module", module, "version", version)
+	return version, nil
+}
+
+// configuredMajorVersion returns the major version of golangci-lint that the configuration file in the current
+// directory is written for, such as v2. Without a configuration file, any version will do, so it returns [Latest].
+func configuredMajorVersion(logger *slog.Logger) (string, error) {
+	file := ConfigFile()
+	if file == "" {
+		return Latest, nil
+	}
+	config, err := ReadConfig(file)
+	if err != nil {
+		return "", err
+	}
+	version := "v" + cmp.Or(config.Version, "1")
+	logger.Debug("found configuration", "file", file, "version", version)
+	return version, nil
+}
+
+// DesiredVersion returns the version of golangci-lint to use when the caller doesn't specify one. A version pinned in
+// go.mod in the given directory wins. Otherwise, it's the latest release of the major version that the configuration
+// file is written for, or [Latest] without a configuration file.
+func DesiredVersion(logger *slog.Logger, modDir string) (string, error) {
+	for _, module := range modules {
+		if version, err := pinnedModuleVersion(logger, modDir, module); err == nil {
+			return version, nil
+		}
+	}
+	return configuredMajorVersion(logger)
+}
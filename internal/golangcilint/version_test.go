@@ -0,0 +1,43 @@
+package golangcilint_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper/internal/golangcilint"
+)
+
+var _ = Describe("InstalledVersion", func() {
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("the fake golangci-lint is a shell script")
+		}
+	})
+
+	version := func(output string) (string, error) {
+		bin := filepath.Join(GinkgoT().TempDir(), "golangci-lint")
+		script := fmt.Sprintf("#!/bin/sh\necho %q\n", output)
+		Expect(os.WriteFile(bin, []byte(script), 0o755)).To(Succeed())
+		return golangcilint.InstalledVersion(bin)
+	}
+
+	It("reads version 1 output", func() {
+		Expect(version("golangci-lint has version 1.57.2 built with go1.22.1 from 77a8601a on 2024-03-28T19:01:11Z")).
+			To(Equal("v1.57.2"))
+	})
+
+	It("reads version 2 output", func() {
+		Expect(version("golangci-lint has version v2.1.6 built with go1.24.2 from eabc2638 on 2025-05-04T15:41:19Z")).
+			To(Equal("v2.1.6"))
+	})
+
+	It("reports output without a version", func() {
+		_, err := version("golangci-lint has no version")
+		Expect(err).To(MatchError(ContainSubstring("Could not find version")))
+	})
+})
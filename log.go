@@ -0,0 +1,85 @@
+package magehelper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/magefile/mage/mg"
+)
+
+// Attribute keys that several tasks use in their log messages.
+const (
+	attrBinary  = "binary"
+	attrError   = "error"
+	attrInput   = "input"
+	attrModule  = "module"
+	attrOutput  = "output"
+	attrVersion = "version"
+)
+
+// verboseLevel is a [slog.Leveler] that follows mage's verbose flag, so the default logger shows debug messages only in
+// verbose mode, just as [LogV] always has.
+type verboseLevel struct{}
+
+func (verboseLevel) Level() slog.Level {
+	if mg.Verbose() {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// dropTime removes the time attribute from log records. Build output is read by people watching it happen, so the
+// timestamps are just noise.
+func dropTime(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) == 0 && attr.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return attr
+}
+
+var defaultLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	Level:       verboseLevel{},
+	ReplaceAttr: dropTime,
+}))
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger configures the logger that magehelper uses for all its messages. Pass nil to restore the default, which
+// writes text to stdout and shows debug messages only when mage runs in verbose mode. Supply a logger with its own
+// handler to choose a different format, such as JSON, or a level that doesn't depend on [mg.Verbose].
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logger returns the logger configured by [SetLogger], or the default logger if none is configured.
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return defaultLogger
+}
+
+// TaskLogger returns [Logger] with attributes identifying the task running in ctx, if any. Tasks should log with this
+// so that messages from concurrent tasks can be told apart.
+func TaskLogger(ctx context.Context) *slog.Logger {
+	l := Logger()
+	if rec := currentTask(ctx); rec != nil {
+		l = l.With(slog.String("task", rec.name))
+		if rec.id != "" {
+			l = l.With(slog.String("task_id", rec.id))
+		}
+	}
+	return l
+}
+
+// LogV formats the message with [fmt.Sprintf] and sends it to [Logger] at debug level, which the default logger shows
+// only if [mg.Verbose] is true.
+//
+// Deprecated: Use [Logger] or [TaskLogger] instead, which accept attributes in place of formatting.
+func LogV(msg string, args ...any) {
+	Logger().Debug(strings.TrimSuffix(fmt.Sprintf(msg, args...), "\n"))
+}
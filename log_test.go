@@ -0,0 +1,56 @@
+package magehelper_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+)
+
+type logTestTask struct{}
+
+func (logTestTask) Name() string {
+	return "log-test"
+}
+
+func (logTestTask) ID() string {
+	return "log-test-id"
+}
+
+func (logTestTask) Run(ctx context.Context) error {
+	magehelper.TaskLogger(ctx).Info("hello", "package", "example")
+	return nil
+}
+
+var _ = Describe("Logger", func() {
+	var buf bytes.Buffer
+
+	BeforeEach(func() {
+		buf.Reset()
+		magehelper.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+		DeferCleanup(magehelper.SetLogger, (*slog.Logger)(nil))
+	})
+
+	It("identifies the running task", func(ctx context.Context) {
+		Expect(magehelper.Traced(logTestTask{}).Run(ctx)).To(Succeed())
+
+		var record map[string]any
+		Expect(json.Unmarshal(buf.Bytes(), &record)).To(Succeed())
+		Expect(record).To(SatisfyAll(
+			HaveKeyWithValue("msg", "hello"),
+			HaveKeyWithValue("task", "log-test"),
+			HaveKeyWithValue("task_id", "log-test-id"),
+			HaveKeyWithValue("package", "example"),
+		))
+	})
+
+	It("honors the configured level", func() {
+		magehelper.LogV("hidden %d\n", 1)
+		Expect(buf.String()).To(BeEmpty())
+	})
+})
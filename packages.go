@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/magefile/mage/mg"
 	"github.com/magefile/mage/sh"
@@ -108,24 +109,72 @@ func (pkg Package) IndirectGoFiles() []string {
 
 // Packages holds the results of the [LoadDependencies] function. This variable is only valid after that function runs.
 // Use [mg.Deps] or similar to make sure dependencies are loaded before referring to this variable.
+//
+// Deprecated: [ReloadPackages] replaces this variable, so reading it while another task might be generating code is a
+// data race. Use [LoadedPackages] instead.
 var Packages = map[string]Package{}
 
-// LoadDependencies populates the global [Packages] variable. It's suitable for use with [mg.Deps] or [mg.CtxDeps].
-func LoadDependencies(context.Context) error {
-	dependencies, err := sh.Output(mg.GoCmd(), "list", "-json", "./...")
+// packagesLock guards [Packages] against concurrent replacement by [ReloadPackages].
+var packagesLock sync.RWMutex
+
+// LoadedPackages returns the packages that [LoadDependencies] loaded, or that [ReloadPackages] loaded most recently.
+// It's safe to call while other tasks reload the packages. The returned map is never modified, so callers may keep
+// using it after a reload; they'll just see the packages as they were before.
+func LoadedPackages() map[string]Package {
+	packagesLock.RLock()
+	defer packagesLock.RUnlock()
+	return Packages
+}
+
+// LoadDependencies loads the packages that [LoadedPackages] returns. It's suitable for use with [mg.Deps] or
+// [mg.CtxDeps].
+func LoadDependencies(ctx context.Context) error {
+	return RunTask(ctx, newFuncTask("Load dependencies", LoadDependencies), loadPackages)
+}
+
+// ReloadPackages runs go list again to replace the packages that [LoadedPackages] returns. Tasks that generate new
+// packages or new Go files call it after generating code, so the generated files count as sources for [Build] and for
+// tests, and later tasks see the new packages. Unlike [LoadDependencies], it does its work every time it's called.
+func ReloadPackages(ctx context.Context) error {
+	return loadPackages(ctx)
+}
+
+func loadPackages(context.Context) error {
+	pkgs, err := ListPackages()
 	if err != nil {
 		return err
 	}
-	dec := json.NewDecoder(strings.NewReader(dependencies))
+	packagesLock.Lock()
+	defer packagesLock.Unlock()
+	Packages = pkgs
+	return nil
+}
+
+// ListPackages runs go list with the given build tags and returns the packages it reports, keyed by import path. Use it
+// for a view of the packages as they're seen with tags that ordinary builds don't use; it doesn't change
+// [LoadedPackages].
+func ListPackages(tags ...string) (map[string]Package, error) {
+	args := slices.Concat([]string{"list", "-json"}, formatTags(goTagOpt, tags), []string{"./..."})
+	output, err := sh.Output(mg.GoCmd(), args...)
+	if err != nil {
+		return nil, err
+	}
+	return decodePackages(output)
+}
+
+// decodePackages parses the output of go list -json.
+func decodePackages(output string) (map[string]Package, error) {
+	pkgs := map[string]Package{}
+	dec := json.NewDecoder(strings.NewReader(output))
 	for {
 		var pkg Package
-		switch err = dec.Decode(&pkg); err {
+		switch err := dec.Decode(&pkg); err {
 		case io.EOF:
-			return nil
+			return pkgs, nil
 		case nil:
-			Packages[pkg.ImportPath] = pkg
+			pkgs[pkg.ImportPath] = pkg
 		default:
-			return err
+			return nil, err
 		}
 	}
 }
@@ -3,6 +3,7 @@ package magehelper_test
 import (
 	"context"
 	"path"
+	"sync"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -24,22 +25,40 @@ var _ = Describe("LoadDependencies", func() {
 	})
 
 	It("includes known packages", func() {
-		Expect(magehelper.Packages).To(SatisfyAll(
+		Expect(magehelper.LoadedPackages()).To(SatisfyAll(
 			HaveKey(thisPackage),
 			HaveKey(path.Join(thisPackage, "tools")),
 		))
 	})
 
+	It("matches the untagged package list", func() {
+		Expect(magehelper.ListPackages()).To(HaveLen(len(magehelper.LoadedPackages())))
+	})
+
+	It("reloads safely while other tasks use the packages", func(ctx context.Context) {
+		// Like two code generators running in parallel, each goroutine reloads the packages and then reads them. Run
+		// with -race to detect unguarded access.
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Go(func() {
+				defer GinkgoRecover()
+				Expect(magehelper.ReloadPackages(ctx)).To(Succeed())
+				Expect(magehelper.LoadedPackages()).To(HaveKey(thisPackage))
+			})
+		}
+		wg.Wait()
+	})
+
 	Context("detects test presence", func() {
 		It("in packages with tests", func() {
-			pkg, ok := magehelper.Packages[thisPackage]
+			pkg, ok := magehelper.LoadedPackages()[thisPackage]
 			Expect(ok).To(BeTrue(), "Package list should include %s", thisPackage)
 			Expect(pkg.HasTest()).To(BeTrue(), "Package should have a test (i.e., this one)")
 		})
 
 		It("in packages without tests", func() {
 			notest := path.Join(thisPackage, "notest")
-			pkg, ok := magehelper.Packages[notest]
+			pkg, ok := magehelper.LoadedPackages()[notest]
 			Expect(ok).To(BeTrue(), "Package list should include %s", notest)
 			Expect(pkg.HasTest()).To(BeFalse(), "Package should not have a test; found %#v", pkg.TestFiles())
 		})
@@ -0,0 +1,182 @@
+package magehelper
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/magefile/mage/target"
+)
+
+// ExplainEnv is the environment variable that enables explain mode when set to a true value, as interpreted by
+// [strconv.ParseBool].
+const ExplainEnv = "MAGEHELPER_EXPLAIN"
+
+var explain atomic.Bool
+
+// SetExplain enables or disables explain mode for the whole process. In explain mode, whenever a magehelper task
+// decides that an output is stale, it says why: which input is newer than the output, which input is missing, or which
+// recorded property, such as a tool version, has changed.
+func SetExplain(enabled bool) {
+	explain.Store(enabled)
+}
+
+// Explain reports whether explain mode is active, either because of [SetExplain] or because of [ExplainEnv].
+func Explain() bool {
+	if explain.Load() {
+		return true
+	}
+	b, _ := strconv.ParseBool(os.Getenv(ExplainEnv))
+	return b
+}
+
+// LogExplain logs the message with the given attributes at info level if [Explain] is true.
+func LogExplain(ctx context.Context, msg string, args ...any) {
+	if Explain() {
+		TaskLogger(ctx).Info(msg, append([]any{slog.Bool("explain", true)}, args...)...)
+	}
+}
+
+// staleCheck describes one staleness decision so that dry-run and explain modes can report it.
+type staleCheck struct {
+	dst       string
+	sources   []string
+	recursive bool
+
+	// pending is a source that a dependency would have created or replaced if not for dry-run mode.
+	pending string
+	stale   bool
+	err     error
+	dstTime time.Time
+}
+
+func (c *staleCheck) reportDecision(ctx context.Context) {
+	switch {
+	case c.err != nil:
+		LogDryRun(ctx, "cannot determine whether output is up to date", attrOutput, c.dst, attrError, c.err)
+	case c.stale:
+		LogDryRun(ctx, "output is out of date", attrOutput, c.dst)
+	default:
+		LogDryRun(ctx, "output is up to date", attrOutput, c.dst)
+	}
+}
+
+// explainSource reports whether a single source file is missing or newer than the output.
+func (c *staleCheck) explainSource(ctx context.Context, src string, info fs.FileInfo, err error) {
+	switch {
+	case err != nil:
+		LogExplain(ctx, "input is missing", attrOutput, c.dst, attrInput, src, attrError, err)
+	case info.ModTime().After(c.dstTime):
+		LogExplain(ctx, "input is newer than output", attrOutput, c.dst, attrInput, src,
+			"input_time", info.ModTime(), "output_time", c.dstTime)
+	default:
+		// This input doesn't contribute to the output being stale.
+	}
+}
+
+// explainTree reports each file in the directory tree rooted at dir that makes the output stale.
+func (c *staleCheck) explainTree(ctx context.Context, dir string) {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			c.explainSource(ctx, path, nil, err)
+			return nil
+		}
+		info, err := d.Info()
+		c.explainSource(ctx, path, info, err)
+		return nil
+	})
+}
+
+// explainSources reports each source that makes the output stale. For a recursive check, directories are searched as
+// they are by [target.Dir].
+func (c *staleCheck) explainSources(ctx context.Context) {
+	if c.pending != "" {
+		LogExplain(ctx, "input would be replaced by a dependency", attrOutput, c.dst, attrInput, c.pending)
+		return
+	}
+	for _, src := range c.sources {
+		info, err := os.Stat(src)
+		if err == nil && info.IsDir() && c.recursive {
+			c.explainTree(ctx, src)
+		} else {
+			c.explainSource(ctx, src, info, err)
+		}
+	}
+}
+
+// explain reports why the output is stale or why its staleness couldn't be determined. Callers often discard the
+// error from a staleness check, so explain mode mentions it here, too.
+func (c *staleCheck) explain(ctx context.Context) {
+	if c.err != nil {
+		LogExplain(ctx, "staleness check failed", attrOutput, c.dst, attrError, c.err)
+	}
+	info, statErr := os.Stat(c.dst)
+	if statErr != nil {
+		LogExplain(ctx, "output does not exist", attrOutput, c.dst)
+		return
+	}
+	c.dstTime = info.ModTime()
+	c.explainSources(ctx)
+}
+
+// decide determines whether the output is stale with the given check from [target]. In dry-run mode, sources that a
+// dependency would have created or replaced make the output stale without consulting the file system, where those
+// sources might not exist yet.
+func (c *staleCheck) decide(
+	ctx context.Context, check func(dst string, sources ...string) (bool, error),
+) (bool, error) {
+	if idx := slices.IndexFunc(c.sources, pending); idx != -1 {
+		c.stale, c.pending = true, c.sources[idx]
+	} else {
+		c.stale, c.err = check(c.dst, c.sources...)
+	}
+	return c.report(ctx)
+}
+
+// report logs the decision in dry-run mode and the reasons for it in explain mode.
+func (c *staleCheck) report(ctx context.Context) (bool, error) {
+	if c.err == nil && !c.stale && Forced(ctx) {
+		c.stale = true
+		LogExplain(ctx, "rebuild is forced", attrOutput, c.dst)
+	}
+	c.reportDecision(ctx)
+	if Explain() && (c.stale || c.err != nil) {
+		c.explain(ctx)
+	}
+	return c.stale, c.err
+}
+
+type forceKey struct{}
+
+// WithForce returns a context in which [Stale] and [StaleDir] report every output as out of date, so tasks that run
+// with it rebuild everything they're responsible for. Tasks that Mage has already run won't run again, though.
+func WithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// Forced reports whether ctx, or a context it derives from, came from [WithForce].
+func Forced(ctx context.Context) bool {
+	forced, ok := ctx.Value(forceKey{}).(bool)
+	return ok && forced
+}
+
+// Stale reports whether dst needs to be rebuilt from the given sources, as by [target.Path], or because ctx came from
+// [WithForce]. In dry-run mode, it also reports the decision, and a source that an install task would have installed
+// makes dst stale even if the source doesn't exist yet. In explain mode, it reports which sources make dst stale.
+func Stale(ctx context.Context, dst string, sources ...string) (bool, error) {
+	check := staleCheck{dst: dst, sources: sources}
+	return check.decide(ctx, target.Path)
+}
+
+// StaleDir is like [Stale], but it checks sources as by [target.Dir], so directories among the sources are searched
+// recursively for newer files.
+func StaleDir(ctx context.Context, dst string, sources ...string) (bool, error) {
+	check := staleCheck{dst: dst, sources: sources, recursive: true}
+	return check.decide(ctx, target.Dir)
+}
@@ -0,0 +1,79 @@
+package magehelper_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+)
+
+var _ = Describe("Stale", func() {
+	var (
+		dir, output, input string
+		log                bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		magehelper.SetExplain(true)
+		DeferCleanup(magehelper.SetExplain, false)
+		log.Reset()
+		magehelper.SetLogger(slog.New(slog.NewTextHandler(&log, nil)))
+		DeferCleanup(magehelper.SetLogger, (*slog.Logger)(nil))
+
+		dir = GinkgoT().TempDir()
+		output = filepath.Join(dir, "output")
+		input = filepath.Join(dir, "input")
+		Expect(os.WriteFile(input, nil, 0o644)).To(Succeed())
+		Expect(os.WriteFile(output, nil, 0o644)).To(Succeed())
+		past := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(input, past, past)).To(Succeed())
+	})
+
+	It("reports up-to-date output", func(ctx context.Context) {
+		Expect(magehelper.Stale(ctx, output, input)).To(BeFalse())
+		Expect(log.String()).To(BeEmpty())
+	})
+
+	It("reports a newer input", func(ctx context.Context) {
+		Expect(os.Chtimes(input, time.Now().Add(time.Hour), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(magehelper.Stale(ctx, output, input)).To(BeTrue())
+		Expect(log.String()).To(SatisfyAll(
+			ContainSubstring(`msg="input is newer than output"`),
+			ContainSubstring("input="+input),
+		))
+	})
+
+	It("reports forced output", func(ctx context.Context) {
+		Expect(magehelper.Stale(magehelper.WithForce(ctx), output, input)).To(BeTrue())
+		Expect(log.String()).To(ContainSubstring(`msg="rebuild is forced"`))
+	})
+
+	It("reports a missing output", func(ctx context.Context) {
+		Expect(os.Remove(output)).To(Succeed())
+		Expect(magehelper.Stale(ctx, output, input)).To(BeTrue())
+		Expect(log.String()).To(ContainSubstring(`msg="output does not exist"`))
+	})
+
+	It("fails for a missing input", func(ctx context.Context) {
+		missing := filepath.Join(dir, "missing")
+		_, err := magehelper.Stale(ctx, output, missing)
+		Expect(err).To(HaveOccurred())
+		Expect(log.String()).To(SatisfyAll(
+			ContainSubstring(`msg="input is missing"`),
+			ContainSubstring("input="+missing),
+		))
+	})
+
+	It("searches directories", func(ctx context.Context) {
+		Expect(os.Chtimes(input, time.Now().Add(time.Hour), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(magehelper.StaleDir(ctx, output, dir)).To(BeTrue())
+		Expect(log.String()).To(ContainSubstring("input=" + input))
+	})
+})
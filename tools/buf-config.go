@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// bufPluginImports maps the names of the protoc plugins that [Buf] knows how to install to their import paths.
+var bufPluginImports = map[string]string{
+	"protoc-gen-go":      "google.golang.org/protobuf/cmd/protoc-gen-go",
+	"protoc-gen-go-grpc": "google.golang.org/grpc/cmd/protoc-gen-go-grpc",
+}
+
+// bufGenPlugin is a plugin entry in buf.gen.yaml. Version 1 names local plugins with the name or plugin field, and
+// version 2 uses the local field, which holds a command and its arguments.
+type bufGenPlugin struct {
+	Name   string     `yaml:"name"`
+	Plugin string     `yaml:"plugin"`
+	Local  stringList `yaml:"local"`
+	Out    string     `yaml:"out"`
+}
+
+// command returns the name of the plugin's executable, or the empty string for a remote plugin.
+func (p *bufGenPlugin) command() string {
+	if len(p.Local) > 0 {
+		return filepath.Base(p.Local[0])
+	}
+	name := p.Name + p.Plugin
+	if name == "" || strings.Contains(name, "/") {
+		return ""
+	}
+	return "protoc-gen-" + name
+}
+
+// bufGenConfig holds the parts of buf.gen.yaml that [Buf] needs.
+type bufGenConfig struct {
+	Plugins []bufGenPlugin `yaml:"plugins"`
+}
+
+// bufModule is a module entry in a version 2 buf.yaml.
+type bufModule struct {
+	Path string `yaml:"path"`
+}
+
+// bufConfig holds the parts of buf.yaml that [Buf] needs.
+type bufConfig struct {
+	Modules []bufModule `yaml:"modules"`
+}
+
+// moduleRoots returns the directories holding the workspace's proto files. Version 2 of buf.yaml lists modules, and
+// otherwise, the directory holding buf.yaml is the only module.
+func moduleRoots(dir string) ([]string, error) {
+	var config bufConfig
+	if err := readYAML(filepath.Join(dir, "buf.yaml"), &config); err != nil {
+		return nil, err
+	}
+	if len(config.Modules) == 0 {
+		return []string{dir}, nil
+	}
+	roots := make([]string, 0, len(config.Modules))
+	for _, module := range config.Modules {
+		roots = append(roots, filepath.Join(dir, module.Path))
+	}
+	return roots, nil
+}
+
+// protoFiles returns the .proto files in the given directory tree.
+func protoFiles(root string) (files []string, err error) {
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".proto" {
+			files = append(files, path)
+		}
+		return err
+	})
+	return files, err
+}
+
+// workspaceProtos returns the workspace's module roots and all the .proto files in them.
+func workspaceProtos(dir string) (roots, protos []string, err error) {
+	roots, err = moduleRoots(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, root := range roots {
+		files, err := protoFiles(root)
+		if err != nil {
+			return nil, nil, err
+		}
+		protos = append(protos, files...)
+	}
+	return roots, protos, nil
+}
+
+// generatedGoFiles returns the .pb.go files in the given directory tree, which might not exist yet.
+func generatedGoFiles(dir string) (files []string, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".pb.go") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return files, err
+}
+
+// protoImport matches an import statement in a .proto file.
+var protoImport = regexp.MustCompile(`^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// protoImports returns the import paths of the given .proto file.
+func protoImports(file string) (imports []string, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if match := protoImport.FindStringSubmatch(scanner.Text()); match != nil {
+			imports = append(imports, match[1])
+		}
+	}
+	return imports, scanner.Err()
+}
+
+// resolveImport returns the file that an import path refers to, relative to any of the module roots. Imports that
+// aren't in the workspace, such as the well-known types and dependencies from buf.lock, resolve to the empty string.
+func resolveImport(roots []string, importPath string) string {
+	for _, root := range roots {
+		file := filepath.Join(root, filepath.FromSlash(importPath))
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// localImports returns the workspace files that the given .proto file imports.
+func localImports(roots []string, file string) (files []string, err error) {
+	imports, err := protoImports(file)
+	for _, importPath := range imports {
+		if imported := resolveImport(roots, importPath); imported != "" {
+			files = append(files, imported)
+		}
+	}
+	return files, err
+}
+
+// importClosure returns the given .proto files along with all the workspace files they import, directly or
+// indirectly.
+func importClosure(roots, files []string) ([]string, error) {
+	seen := mapset.NewThreadUnsafeSet(files...)
+	for queue := slices.Clone(files); len(queue) > 0; queue = queue[1:] {
+		imports, err := localImports(roots, queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, slices.DeleteFunc(imports, func(file string) bool { return !seen.Add(file) })...)
+	}
+	return slices.Sorted(slices.Values(seen.ToSlice())), nil
+}
+
+// protoSource matches the header comment in which protoc plugins name the .proto file that they generated a file from.
+var protoSource = regexp.MustCompile(`^// source: (.+)$`)
+
+// generatedSource returns the import path of the .proto file that the given generated file came from, as its header
+// names it, or the empty string if the header doesn't name one.
+func generatedSource(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() && !strings.HasPrefix(scanner.Text(), "package ") {
+		if match := protoSource.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// protoDependencies returns the workspace .proto files that the given generated file depends on: the file it came from
+// and everything that file imports, directly or indirectly. When the source isn't known, or isn't in the workspace,
+// the generated file depends on all the given workspace files.
+func protoDependencies(roots, protos []string, output string) ([]string, error) {
+	source, err := generatedSource(output)
+	if err != nil || source == "" {
+		return protos, err
+	}
+	if file := resolveImport(roots, source); file != "" {
+		return importClosure(roots, []string{file})
+	}
+	return protos, nil
+}
@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+const bufImport = "github.com/bufbuild/buf/cmd/buf"
+
+// BufTask is a Mage task that generates code from Protocol Buffers definitions with buf.
+type BufTask struct {
+	bufBin   string
+	dir      string
+	template string
+	modDir   string
+}
+
+var _ mg.Fn = &BufTask{}
+
+// Buf returns a [mg.Fn] that runs buf generate in the current directory, which should hold buf.yaml and buf.gen.yaml.
+// The task installs buf, along with any of the protoc-gen-go and protoc-gen-go-grpc plugins that buf.gen.yaml uses
+// locally, at the versions in go.mod. The plugins go in the same directory as buf, and that directory comes first in
+// the PATH for buf generate. Since everything is installed with go install, generating code works offline as long as
+// the module cache has the pinned versions and buf.gen.yaml doesn't use remote plugins.
+//
+// The generated files are the .pb.go files in the output directories of the plugins in buf.gen.yaml. Each one is out
+// of date when it's older than buf.yaml, buf.gen.yaml, buf.lock, the installed tools, the .proto file that its header
+// names as its source, or any workspace .proto file that the source imports, directly or indirectly. Imports from
+// dependencies in buf.lock change along with buf.lock itself. A generated file whose header doesn't name its source
+// depends on every .proto file in the workspace. Any out-of-date file makes buf generate everything. After generating
+// code, the task calls [magehelper.ReloadPackages].
+func Buf(bufBin string) *BufTask {
+	return &BufTask{
+		bufBin:   bufBin,
+		dir:      ".",
+		template: "buf.gen.yaml",
+	}
+}
+
+// Dir sets the directory holding buf.yaml and buf.gen.yaml. The default is the current directory. Dir returns the
+// BufTask.
+func (fn *BufTask) Dir(dir string) *BufTask {
+	fn.dir = dir
+	return fn
+}
+
+// Template sets the name of the generation template, relative to the task's directory. The default is buf.gen.yaml.
+// Template returns the BufTask.
+func (fn *BufTask) Template(template string) *BufTask {
+	fn.template = template
+	return fn
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which versions of buf and the
+// plugins this project uses. ModDir returns the BufTask.
+func (fn *BufTask) ModDir(dir string) *BufTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (fn *BufTask) Name() string {
+	return fmt.Sprintf("Buf generate %s", filepath.Join(fn.dir, fn.template))
+}
+
+// ID implements [mg.Fn].
+func (fn *BufTask) ID() string {
+	return fmt.Sprintf("magehelper buf %s", filepath.Join(fn.dir, fn.template))
+}
+
+// Run implements [mg.Fn].
+func (fn *BufTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *BufTask) execute(ctx context.Context) error {
+	var config bufGenConfig
+	if err := readYAML(filepath.Join(fn.dir, fn.template), &config); err != nil {
+		return err
+	}
+	tools := fn.tools(config)
+	magehelper.Deps(ctx, fn.installs(tools)...)
+	return fn.generateIfStale(ctx, config, tools)
+}
+
+// generateIfStale runs buf generate if the generated files are out of date, and then it reloads the packages to pick up
+// any new files.
+func (fn *BufTask) generateIfStale(ctx context.Context, config bufGenConfig, tools map[string]string) error {
+	stale, err := fn.stale(ctx, config, tools)
+	if err != nil || !stale {
+		return err
+	}
+	if err = fn.generate(ctx); err != nil {
+		return err
+	}
+	return magehelper.ReloadPackages(ctx)
+}
+
+// tools returns the binaries to install, mapped to their import paths: buf itself and the local plugins it knows about.
+func (fn *BufTask) tools(config bufGenConfig) map[string]string {
+	binDir := filepath.Dir(fn.bufBin)
+	tools := map[string]string{fn.bufBin: bufImport}
+	for _, plugin := range config.Plugins {
+		if importPath, ok := bufPluginImports[plugin.command()]; ok {
+			tools[filepath.Join(binDir, plugin.command())] = importPath
+		}
+	}
+	return tools
+}
+
+// installs returns the tasks that install the given tools.
+func (fn *BufTask) installs(tools map[string]string) (installs []any) {
+	for _, bin := range slices.Sorted(maps.Keys(tools)) {
+		installs = append(installs, magehelper.Install(bin, tools[bin]).ModDir(fn.modDir))
+	}
+	return installs
+}
+
+// bufInputs holds what's needed to determine the files that each generated file depends on.
+type bufInputs struct {
+	roots  []string
+	protos []string
+	shared []string
+}
+
+// inputs returns the inputs for the workspace: its module roots and .proto files, plus the configuration files and
+// tools that every generated file depends on.
+func (fn *BufTask) inputs(tools map[string]string) (bufInputs, error) {
+	roots, protos, err := workspaceProtos(fn.dir)
+	shared := slices.Concat(fn.configFiles(), slices.Collect(maps.Keys(tools)))
+	return bufInputs{roots: roots, protos: protos, shared: shared}, err
+}
+
+// of returns the files that the given generated file depends on.
+func (in bufInputs) of(output string) ([]string, error) {
+	protos, err := protoDependencies(in.roots, in.protos, output)
+	return slices.Concat(protos, in.shared), err
+}
+
+// configFiles returns buf's configuration files that exist.
+func (fn *BufTask) configFiles() []string {
+	return slices.DeleteFunc([]string{
+		filepath.Join(fn.dir, "buf.yaml"),
+		filepath.Join(fn.dir, fn.template),
+		filepath.Join(fn.dir, "buf.lock"),
+	}, func(file string) bool {
+		_, err := os.Stat(file)
+		return err != nil
+	})
+}
+
+// outputs returns the generated .pb.go files in the plugins' output directories.
+func (fn *BufTask) outputs(config bufGenConfig) ([]string, error) {
+	outputs := mapset.NewThreadUnsafeSet[string]()
+	for _, plugin := range config.Plugins {
+		files, err := generatedGoFiles(filepath.Join(fn.dir, plugin.Out))
+		if err != nil {
+			return nil, err
+		}
+		outputs.Append(files...)
+	}
+	return slices.Sorted(slices.Values(outputs.ToSlice())), nil
+}
+
+// stale reports whether any generated file is missing or out of date.
+func (fn *BufTask) stale(ctx context.Context, config bufGenConfig, tools map[string]string) (bool, error) {
+	outputs, err := fn.outputs(config)
+	if err != nil {
+		return false, err
+	}
+	if len(outputs) == 0 {
+		magehelper.LogExplain(ctx, "no generated files exist", "dir", fn.dir)
+		return true, nil
+	}
+	inputs, err := fn.inputs(tools)
+	if err != nil {
+		return false, err
+	}
+	return anyStaleEach(ctx, outputs, inputs.of)
+}
+
+// generate runs buf generate with the installed plugins first in the PATH.
+func (fn *BufTask) generate(ctx context.Context) error {
+	bufBin, err := filepath.Abs(fn.bufBin)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(bufBin, "generate", "--template", fn.template)
+	cmd.Dir = fn.dir
+	cmd.Env = append(os.Environ(),
+		"PATH="+filepath.Dir(bufBin)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return magehelper.RunCmd(ctx, cmd)
+}
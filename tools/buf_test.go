@@ -0,0 +1,71 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var bufDir = filepath.Join("examples", "buf")
+
+var _ = Describe("Buf", Ordered, func() {
+	commonProto := filepath.Join(bufDir, "proto", "common", "v1", "common.proto")
+	greetProto := filepath.Join(bufDir, "proto", "greet", "v1", "greet.proto")
+	commonGo := filepath.Join(bufDir, "gen", "common", "v1", "common.pb.go")
+	greetGo := filepath.Join(bufDir, "gen", "greet", "v1", "greet.pb.go")
+
+	// setTimes sets the modification times of the given files to the given offsets from now, and restores them
+	// afterward.
+	setTimes := func(offsets map[string]time.Duration) {
+		GinkgoHelper()
+		now := time.Now()
+		for file, offset := range offsets {
+			info, err := os.Stat(file)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.Chtimes, file, info.ModTime(), info.ModTime())
+			Expect(os.Chtimes(file, now.Add(offset), now.Add(offset))).To(Succeed())
+		}
+	}
+
+	BeforeAll(func() {
+		removeAfterAll(filepath.Join(bufDir, "gen"))
+		By("generating code")
+		invokeExample(bufDir, "generate")
+	})
+
+	It("generates code for every proto file", func() {
+		Expect(commonGo).To(BeAnExistingFile())
+		Expect(greetGo).To(BeAnExistingFile())
+	})
+
+	It("doesn't regenerate up-to-date code", func() {
+		Expect(dryRunExample(bufDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("regenerates code when an imported file changes", func() {
+		touchLater(commonProto)
+		Expect(dryRunExample(bufDir, "generate")).To(MatchRegexp(`would run.*buf.* generate`))
+	})
+
+	It("makes only the dependents of a changed file stale", func() {
+		By("changing a file that nothing else imports")
+		setTimes(map[string]time.Duration{
+			commonGo:   time.Hour,
+			greetProto: 90 * time.Minute,
+			greetGo:    2 * time.Hour,
+		})
+		Expect(dryRunExample(bufDir, "generate")).NotTo(ContainSubstring("would run"))
+
+		By("changing a file that another file imports")
+		setTimes(map[string]time.Duration{
+			commonProto: 90 * time.Minute,
+			commonGo:    2 * time.Hour,
+			greetProto:  0,
+			greetGo:     time.Hour,
+		})
+		Expect(dryRunExample(bufDir, "generate")).To(MatchRegexp(`would run.*buf.* generate`))
+	})
+})
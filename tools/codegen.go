@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+// CodegenTask is a Mage task that runs an arbitrary code generator, installed from a Go module, when its outputs are
+// out of date.
+type CodegenTask struct {
+	bin     string
+	module  string
+	command string
+	inputs  []string
+	outputs []string
+	modDir  string
+}
+
+var _ mg.Fn = &CodegenTask{}
+
+// codegenData is the data for expanding a [CodegenTask] command template.
+type codegenData struct {
+	// Bin is the generator binary.
+	Bin string
+	// Inputs holds the input files, expanded from the input globs.
+	Inputs []string
+	// Outputs holds the output files.
+	Outputs []string
+}
+
+// codegenFuncs are the functions available to [CodegenTask] command templates.
+var codegenFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+}
+
+// Codegen returns a [mg.Fn] that runs a code generator. The generator is the given binary, installed from the given
+// module as by [magehelper.Install], so its version comes from go.mod. The command is a [text/template] that expands to
+// the command line to run. Its data has fields Bin, Inputs, and Outputs, and the quote function quotes a word that
+// might contain spaces. After expansion, the command is split into words as in a go:generate directive. For example:
+//
+//	tools.Codegen("bin/enumer", "github.com/dmarkham/enumer",
+//		"{{.Bin}} -type Color -output {{index .Outputs 0}} .").
+//		Inputs("color.go").
+//		Outputs("color_enumer.go")
+//
+// The generator runs when any output is missing or older than any input or the binary. Inputs are file globs; the
+// outputs are excluded from them, so a glob like *.go can include the package where the outputs go. Running the task
+// with a context from [magehelper.WithForce], as [VerifyGenerated] does, runs the generator regardless.
+func Codegen(bin, module, command string) *CodegenTask {
+	return &CodegenTask{
+		bin:     bin,
+		module:  module,
+		command: command,
+	}
+}
+
+// Inputs adds file globs for the generator's inputs. Inputs returns the CodegenTask.
+func (fn *CodegenTask) Inputs(globs ...string) *CodegenTask {
+	fn.inputs = append(fn.inputs, globs...)
+	return fn
+}
+
+// Outputs adds the names of the files the generator writes. Outputs returns the CodegenTask.
+func (fn *CodegenTask) Outputs(files ...string) *CodegenTask {
+	fn.outputs = append(fn.outputs, files...)
+	return fn
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of the generator
+// this project uses. ModDir returns the CodegenTask.
+func (fn *CodegenTask) ModDir(dir string) *CodegenTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (fn *CodegenTask) Name() string {
+	return fmt.Sprintf("Codegen %s (%s)", fn.bin, strings.Join(fn.outputs, ", "))
+}
+
+// ID implements [mg.Fn].
+func (fn *CodegenTask) ID() string {
+	return fmt.Sprintf("magehelper codegen %s %s", fn.bin, strings.Join(fn.outputs, ","))
+}
+
+// Run implements [mg.Fn].
+func (fn *CodegenTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *CodegenTask) execute(ctx context.Context) error {
+	if len(fn.outputs) == 0 {
+		return errors.New("code generator has no outputs")
+	}
+	magehelper.Deps(ctx, magehelper.Install(fn.bin, fn.module).ModDir(fn.modDir))
+	return fn.generate(ctx)
+}
+
+// generate runs the generator if the outputs are out of date.
+func (fn *CodegenTask) generate(ctx context.Context) error {
+	inputs, err := fn.inputFiles()
+	if err != nil {
+		return err
+	}
+	stale, err := fn.stale(ctx, inputs)
+	if err != nil || !stale {
+		return err
+	}
+	words, err := fn.commandWords(inputs)
+	if err != nil {
+		return err
+	}
+	return magehelper.RunV(ctx, words[0], words[1:]...)
+}
+
+// inputFiles expands the input globs, leaving out the outputs. It's an error for a glob not to match any files.
+func (fn *CodegenTask) inputFiles() (files []string, err error) {
+	for _, pattern := range fn.inputs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("input %s matches no files", pattern)
+		}
+		files = append(files, matches...)
+	}
+	return slices.DeleteFunc(files, func(file string) bool { return slices.Contains(fn.outputs, file) }), nil
+}
+
+// anyStale reports whether any of the outputs is out of date with respect to the inputs.
+func anyStale(ctx context.Context, outputs, inputs []string) (bool, error) {
+	for _, output := range outputs {
+		stale, err := magehelper.Stale(ctx, output, inputs...)
+		if err != nil || stale {
+			return stale, err
+		}
+	}
+	return false, nil
+}
+
+// anyStaleEach reports whether any output is older than the inputs that the given function returns for it.
+func anyStaleEach(ctx context.Context, outputs []string, inputs func(string) ([]string, error)) (bool, error) {
+	for _, output := range outputs {
+		files, err := inputs(output)
+		if err != nil {
+			return false, err
+		}
+		if stale, err := magehelper.Stale(ctx, output, files...); err != nil || stale {
+			return stale, err
+		}
+	}
+	return false, nil
+}
+
+// stale reports whether any output is out of date.
+func (fn *CodegenTask) stale(ctx context.Context, inputs []string) (bool, error) {
+	return anyStale(ctx, fn.outputs, append(slices.Clone(inputs), fn.bin))
+}
+
+// expandCommand expands the command template.
+func (fn *CodegenTask) expandCommand(inputs []string) (string, error) {
+	tmpl, err := template.New(fn.bin).Funcs(codegenFuncs).Parse(fn.command)
+	if err != nil {
+		return "", err
+	}
+	var command strings.Builder
+	err = tmpl.Execute(&command, codegenData{Bin: fn.bin, Inputs: inputs, Outputs: fn.outputs})
+	return command.String(), err
+}
+
+// commandWords expands the command template and splits the result into words.
+func (fn *CodegenTask) commandWords(inputs []string) ([]string, error) {
+	command, err := fn.expandCommand(inputs)
+	if err != nil {
+		return nil, err
+	}
+	words, err := splitWords(command)
+	if err == nil && len(words) == 0 {
+		err = fmt.Errorf("command %q is empty", command)
+	}
+	return words, err
+}
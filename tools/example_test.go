@@ -0,0 +1,62 @@
+package tools_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/magefile/mage/mage"
+	"github.com/rkennedy/magehelper"
+)
+
+// invokeExampleStatus runs the given target of the example project in dir and returns the target's output and exit
+// status.
+func invokeExampleStatus(dir, target string) (string, int) {
+	var out strings.Builder
+	status := mage.Invoke(mage.Invocation{
+		Dir:    dir,
+		Stdout: io.MultiWriter(&out, GinkgoWriter),
+		Stderr: GinkgoWriter,
+		Args:   []string{target},
+	})
+	return out.String(), status
+}
+
+// invokeExample is like [invokeExampleStatus], but it expects the target to succeed.
+func invokeExample(dir, target string) string {
+	GinkgoHelper()
+	out, status := invokeExampleStatus(dir, target)
+	Expect(status).To(Equal(0), "%s in %s should exit successfully.", target, dir)
+	return out
+}
+
+// dryRunExample is like [invokeExample], but it runs the target in dry-run mode, so the output lists the commands that
+// would have run.
+func dryRunExample(dir, target string) string {
+	GinkgoHelper()
+	GinkgoT().Setenv(magehelper.DryRunEnv, "true")
+	return invokeExample(dir, target)
+}
+
+// touchLater makes the given file look as though it will be modified an hour from now, so everything generated from it
+// is out of date. The file's modification time is restored when the spec finishes.
+func touchLater(file string) {
+	GinkgoHelper()
+	info, err := os.Stat(file)
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(os.Chtimes, file, info.ModTime(), info.ModTime())
+	later := time.Now().Add(time.Hour)
+	Expect(os.Chtimes(file, later, later)).To(Succeed())
+}
+
+// removeAfterAll removes the given generated files and directories once the specs of the enclosing ordered container
+// finish. Call it from BeforeAll.
+func removeAfterAll(paths ...string) {
+	for _, path := range paths {
+		DeferCleanup(os.RemoveAll, path)
+	}
+}
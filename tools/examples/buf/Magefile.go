@@ -0,0 +1,23 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's Buf tool to generate code
+// from Protocol Buffers definitions during a build. Refer to the Generate
+// target.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var (
+	bufBin = filepath.Join("bin", "buf")
+)
+
+// Generate updates generated code.
+func Generate(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.Buf(bufBin))
+}
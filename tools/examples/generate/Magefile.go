@@ -0,0 +1,31 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's GoGenerate tool to run
+// go:generate directives during a build, and its Codegen tool to run a
+// generator without a directive. Refer to the Generate and Colors targets.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var (
+	stringerBin = filepath.Join("bin", "stringer")
+)
+
+// Generate runs the go:generate directives that are out of date.
+func Generate(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.GoGenerate().Stringer(stringerBin))
+}
+
+// Colors generates strings for the colors package.
+func Colors(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.Codegen(stringerBin, "golang.org/x/tools/cmd/stringer",
+		"{{.Bin}} -type Color -output {{index .Outputs 0}} {{range .Inputs}}{{quote .}} {{end}}").
+		Inputs(filepath.Join("colors", "*.go")).
+		Outputs(filepath.Join("colors", "color_string.go")))
+}
@@ -0,0 +1,29 @@
+// Command gennames generates a Go file declaring the names listed in a text file. It's meant to run from a go:generate
+// directive, which supplies the package name.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const outputMode = 0o644
+
+func main() {
+	out := flag.String("out", "", "output file")
+	flag.Parse()
+
+	content, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "// Code generated by gennames; DO NOT EDIT.\n\npackage %s\n\nvar names = %#v\n",
+		os.Getenv("GOPACKAGE"), strings.Fields(string(content)))
+	if err = os.WriteFile(*out, []byte(b.String()), outputMode); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,11 @@
+// Package colors demonstrates running a code generator with magehelper's Codegen task.
+package colors
+
+// Color is an enum type for stringer to process.
+type Color int
+
+// These constants are found by stringer.
+const (
+	Red Color = iota
+	Green
+)
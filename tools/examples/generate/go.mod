@@ -0,0 +1,19 @@
+module github.com/rkennedy/magehelper/examples/generate
+
+go 1.25.0
+
+require (
+	github.com/magefile/mage v1.15.0
+	github.com/rkennedy/magehelper v0.0.0-20251001024801-b9bfd68621b2
+	golang.org/x/tools v0.49.0
+)
+
+require (
+	github.com/deckarep/golang-set/v2 v2.8.0 // indirect
+	golang.org/x/mod v0.39.0 // indirect
+	golang.org/x/sync v0.22.0 // indirect
+	golang.org/x/text v0.41.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/rkennedy/magehelper => ../../..
@@ -0,0 +1,28 @@
+// Package generate is an example to demonstrate bits of the magehelper package that it doesn't exercise for itself.
+package main
+
+import (
+	"fmt"
+)
+
+// Pill is an enum type for the stringer directive below, which magehelper runs with its own installed stringer.
+type Pill int
+
+// These are members of the enum that will be stringized.
+const (
+	Placebo Pill = iota
+	Aspirin
+)
+
+//go:generate stringer -type=Pill -output=pill_string.go $GOFILE
+
+//go:generate -command names go run ./cmd/gennames
+
+// The names directive only runs when this file or names.txt changes.
+//
+//magehelper:inputs names.txt
+//go:generate names -out names_gen.go names.txt
+
+func main() {
+	_, _ = fmt.Println(Aspirin, names)
+}
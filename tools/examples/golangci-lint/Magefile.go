@@ -0,0 +1,20 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's GolangciLint tool to lint every
+// package in the project. Refer to the Lint target.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var golangciLintBin = filepath.Join("bin", "golangci-lint")
+
+// Lint runs golangci-lint on every package in the project.
+func Lint(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.GolangciLint(golangciLintBin, "v1.64.8"))
+}
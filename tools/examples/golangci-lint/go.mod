@@ -0,0 +1,18 @@
+module github.com/rkennedy/magehelper/examples/golangci-lint
+
+go 1.25
+
+replace github.com/rkennedy/magehelper => ../../..
+
+require (
+	github.com/magefile/mage v1.15.0
+	github.com/rkennedy/magehelper v0.0.0-20251001024801-b9bfd68621b2
+)
+
+require (
+	github.com/deckarep/golang-set/v2 v2.8.0 // indirect
+	golang.org/x/mod v0.28.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	golang.org/x/tools v0.37.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
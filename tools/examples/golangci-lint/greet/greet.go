@@ -0,0 +1,7 @@
+// Package greet is a second package for golangci-lint to check.
+package greet
+
+// Greeting returns a greeting for the given name.
+func Greeting(name string) string {
+	return "Hello, " + name
+}
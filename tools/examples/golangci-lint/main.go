@@ -0,0 +1,12 @@
+// Package main is an example to demonstrate running golangci-lint on every package in a project.
+package main
+
+import (
+	"fmt"
+
+	"github.com/rkennedy/magehelper/examples/golangci-lint/greet"
+)
+
+func main() {
+	_, _ = fmt.Println(greet.Greeting("world"))
+}
@@ -0,0 +1,15 @@
+package typed
+
+import (
+	"io"
+
+	"github.com/rkennedy/magehelper/examples/mockgen/typed/flush"
+)
+
+// Cache is mocked from the project root. Its mocks depend on this file and on the file declaring flush.Flusher, but not
+// on the rest of the package. The declaration of io.Closer only changes along with the Go version, so it isn't tracked.
+type Cache interface {
+	io.Closer
+	flush.Flusher
+	Get(key string) (string, bool)
+}
@@ -0,0 +1,7 @@
+// Package flush declares an interface that typed.Cache embeds, so the mocks of Cache depend on this package's file too.
+package flush
+
+// Flusher writes out any buffered data.
+type Flusher interface {
+	Flush() error
+}
@@ -0,0 +1,9 @@
+package typed
+
+import "io"
+
+// store is unexported, so mockgen can only mock it in source mode.
+type store interface {
+	io.Closer
+	get(key string) (string, bool)
+}
@@ -0,0 +1,3 @@
+// Package typed shows how mockgen.yaml options customize the generated mocks. The mock definition asks for typed mocks
+// with a custom mock name and file name.
+package typed
@@ -0,0 +1,29 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's OapiCodegen tool to generate
+// code from an OpenAPI specification during a build. Refer to the Generate
+// target.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var (
+	oapiCodegenBin = filepath.Join("bin", "oapi-codegen")
+)
+
+// Generate updates generated code.
+func Generate(ctx context.Context) {
+	mg.CtxDeps(ctx,
+		tools.OapiCodegen(oapiCodegenBin, filepath.Join("api", "common", "openapi.yaml"),
+			filepath.Join("api", "common", "types.cfg.yaml")),
+		tools.OapiCodegen(oapiCodegenBin, filepath.Join("api", "openapi.yaml"),
+			filepath.Join("api", "types.cfg.yaml"),
+			filepath.Join("api", "client.cfg.yaml")),
+	)
+}
@@ -0,0 +1,5 @@
+// Package oapi-codegen is an example to demonstrate bits of the magehelper package that it doesn't exercise for itself.
+package main
+
+func main() {
+}
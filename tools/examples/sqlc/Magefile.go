@@ -0,0 +1,29 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's Sqlc tool to generate code
+// from SQL during a build and to check the generated code in continuous
+// integration. Refer to the Generate and Check targets.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var (
+	sqlcBin = filepath.Join("bin", "sqlc")
+)
+
+// Generate updates generated code.
+func Generate(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.Sqlc(sqlcBin))
+}
+
+// Check confirms that the queries are valid and that the generated code is
+// current.
+func Check(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.SqlcCheck(sqlcBin).Vet())
+}
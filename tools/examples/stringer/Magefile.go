@@ -24,6 +24,9 @@ func Generate(ctx context.Context) {
 	mg.CtxDeps(ctx,
 		tools.Stringer(stringerBin, "SameDirectory", "samedir-strings.go", "main.go"),
 		tools.Stringer(stringerBin, "Subdirectory", "subdir/subdir-strings.go", "subdir/subdir.go"),
+		tools.StringerPackage(stringerBin, "github.com/rkennedy/magehelper/examples/stringer/multi", "Color", "Shape").
+			LineComment().
+			TrimPrefix("Color"),
 	)
 }
 
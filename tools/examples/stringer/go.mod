@@ -1,22 +1,22 @@
 module github.com/rkennedy/magehelper/examples/stringer
 
-go 1.25
+go 1.25.0
 
 require (
 	github.com/magefile/mage v1.15.0
 	github.com/onsi/gomega v1.38.2
 	github.com/rkennedy/magehelper v0.0.0-20251001024801-b9bfd68621b2
-	golang.org/x/tools v0.37.0
+	golang.org/x/tools v0.48.0
 )
 
 require (
 	github.com/deckarep/golang-set/v2 v2.8.0 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
-	golang.org/x/mod v0.28.0 // indirect
-	golang.org/x/net v0.44.0 // indirect
-	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/text v0.29.0 // indirect
+	golang.org/x/mod v0.38.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sync v0.22.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
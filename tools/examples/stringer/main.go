@@ -8,6 +8,7 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	"github.com/rkennedy/magehelper/examples/stringer/multi"
 	"github.com/rkennedy/magehelper/examples/stringer/subdir"
 )
 
@@ -39,5 +40,7 @@ func main() {
 	g := NewWithT(&test{})
 	g.Expect(fmt.Sprintf("%s", subdir.First)).To(Equal("First"))
 	g.Expect(fmt.Sprintf("%s", Second)).To(Equal("Second"))
+	g.Expect(fmt.Sprintf("%s", multi.ColorGreen)).To(Equal("Green"))
+	g.Expect(fmt.Sprintf("%s", multi.ShapeSquare)).To(Equal("square"))
 	os.Exit(exitCode)
 }
@@ -0,0 +1,20 @@
+// Package multi demonstrates generating strings for several enum types in a package at once.
+package multi
+
+// Color is an enum type whose strings omit the common prefix of its constants.
+type Color int
+
+// These constants are found by stringer.
+const (
+	ColorRed Color = iota
+	ColorGreen
+)
+
+// Shape is an enum type whose strings come from line comments.
+type Shape int
+
+// These constants are found by stringer.
+const (
+	ShapeCircle Shape = iota // circle
+	ShapeSquare              // square
+)
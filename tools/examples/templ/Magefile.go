@@ -0,0 +1,43 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's Templ tool to generate code
+// from templ components during a build. Refer to the Generate target.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/magefile/mage/sh"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var (
+	templBin = filepath.Join("bin", "templ")
+	program  = filepath.Join("bin", "example")
+)
+
+// Generate updates generated code.
+func Generate(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.Templ(templBin))
+}
+
+// Watch regenerates code whenever a component changes.
+func Watch(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.TemplWatch(templBin))
+}
+
+// Build builds the example program.
+func Build(ctx context.Context) error {
+	mg.CtxDeps(ctx, Generate)
+	return magehelper.Build(ctx, program)
+}
+
+// Test runs the example, confirming that templ has run and produced the
+// expected components.
+func Test(ctx context.Context) error {
+	mg.CtxDeps(ctx, Build)
+	return sh.RunV(program)
+}
@@ -0,0 +1,2 @@
+// Package components holds templ components for the example page.
+package components
@@ -0,0 +1,34 @@
+// Package templ is an example to demonstrate bits of the magehelper package that it doesn't exercise for itself.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	. "github.com/onsi/gomega"
+)
+
+type test struct{}
+
+func (*test) Helper() {}
+
+var exitCode int
+
+func (*test) Fatalf(format string, args ...any) {
+	s := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(s, "\n") {
+		s = s + "\n"
+	}
+	_, _ = fmt.Fprint(os.Stderr, s)
+	exitCode = 1
+}
+
+func main() {
+	g := NewWithT(&test{})
+	var out strings.Builder
+	g.Expect(page("world").Render(context.Background(), &out)).To(Succeed())
+	g.Expect(out.String()).To(Equal("<main><p>Hello, world!</p></main>"))
+	os.Exit(exitCode)
+}
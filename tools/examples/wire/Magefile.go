@@ -0,0 +1,45 @@
+//go:build mage
+
+// This magefile demonstrates using magehelper's Wire tool to generate code
+// for dependency injection during a build and to check it in continuous
+// integration. Refer to the Generate and Check targets.
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/magefile/mage/mg"
+	"github.com/magefile/mage/sh"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+var (
+	wireBin = filepath.Join("bin", "wire")
+	program = filepath.Join("bin", "example")
+)
+
+// Generate updates generated code.
+func Generate(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.Wire(wireBin))
+}
+
+// Check confirms that the dependency graph is valid and that the generated
+// code is current.
+func Check(ctx context.Context) {
+	mg.CtxDeps(ctx, tools.WireCheck(wireBin))
+}
+
+// Build builds the example program.
+func Build(ctx context.Context) error {
+	mg.CtxDeps(ctx, Generate)
+	return magehelper.Build(ctx, program)
+}
+
+// Test runs the example, confirming that Wire has run and produced the
+// expected injector.
+func Test(ctx context.Context) error {
+	mg.CtxDeps(ctx, Build)
+	return sh.RunV(program)
+}
@@ -0,0 +1,26 @@
+module github.com/rkennedy/magehelper/examples/wire
+
+go 1.25.0
+
+replace github.com/rkennedy/magehelper => ../../..
+
+require (
+	github.com/google/wire v0.7.0
+	github.com/magefile/mage v1.15.0
+	github.com/onsi/gomega v1.38.2
+	github.com/rkennedy/magehelper v0.0.0-20251001024801-b9bfd68621b2
+)
+
+require (
+	github.com/deckarep/golang-set/v2 v2.8.0 // indirect
+	github.com/google/go-cmp v0.7.0 // indirect
+	github.com/google/subcommands v1.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.yaml.in/yaml/v3 v3.0.4 // indirect
+	golang.org/x/mod v0.39.0 // indirect
+	golang.org/x/net v0.58.0 // indirect
+	golang.org/x/sync v0.22.0 // indirect
+	golang.org/x/text v0.41.0 // indirect
+	golang.org/x/tools v0.49.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,30 @@
+// Package greet provides the components that Wire assembles in the example.
+package greet
+
+import "github.com/google/wire"
+
+// Message is the text that a Greeter says.
+type Message string
+
+// NewMessage provides a Message.
+func NewMessage(phrase string) Message {
+	return Message(phrase)
+}
+
+// Greeter says its message.
+type Greeter struct {
+	message Message
+}
+
+// NewGreeter provides a Greeter.
+func NewGreeter(message Message) *Greeter {
+	return &Greeter{message: message}
+}
+
+// Greet returns the greeter's message.
+func (g *Greeter) Greet() string {
+	return string(g.message)
+}
+
+// ProviderSet holds the package's providers.
+var ProviderSet = wire.NewSet(NewMessage, NewGreeter)
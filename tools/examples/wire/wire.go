@@ -0,0 +1,14 @@
+//go:build wireinject
+
+package main
+
+import (
+	"github.com/google/wire"
+
+	"github.com/rkennedy/magehelper/examples/wire/greet"
+)
+
+func initializeGreeter(phrase string) *greet.Greeter {
+	wire.Build(greet.ProviderSet)
+	return nil
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"go/build"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rkennedy/magehelper"
+)
+
+const (
+	// generatePrefix starts a go:generate directive.
+	generatePrefix = "//go:generate "
+	// inputsPrefix starts a comment that declares the inputs of the go:generate directive on the next line.
+	inputsPrefix = "//magehelper:inputs "
+	// commandFlag starts a go:generate directive that defines an alias for a command.
+	commandFlag = "-command"
+)
+
+// generateDirective is a go:generate directive found in a package's source.
+type generateDirective struct {
+	// file is the full name of the file holding the directive.
+	file string
+	line int
+	// pkgName is the name of the package the file belongs to, which is the package's _test package for external test
+	// files.
+	pkgName string
+	// words is the command to run, after substituting any alias, but before expanding variables.
+	words []string
+	// inputs holds the globs, relative to the file's directory, of the directive's declared inputs.
+	inputs []string
+}
+
+func (d *generateDirective) String() string {
+	return fmt.Sprintf("%s:%d: %s", d.file, d.line, strings.Join(d.words, " "))
+}
+
+// vars returns the variables that go generate defines for the directive.
+func (d *generateDirective) vars() map[string]string {
+	return map[string]string{
+		"GOARCH":    cmp.Or(os.Getenv("GOARCH"), runtime.GOARCH),
+		"GOOS":      cmp.Or(os.Getenv("GOOS"), runtime.GOOS),
+		"GOROOT":    build.Default.GOROOT,
+		"GOFILE":    filepath.Base(d.file),
+		"GOLINE":    strconv.Itoa(d.line),
+		"GOPACKAGE": d.pkgName,
+		"DOLLAR":    "$",
+	}
+}
+
+// env returns the environment variables to add for running the directive's command.
+func (d *generateDirective) env() (env []string) {
+	vars := d.vars()
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		env = append(env, name+"="+vars[name])
+	}
+	return env
+}
+
+// expandedWords returns the directive's command with variables expanded in each word. Besides the go generate
+// variables, any environment variable may be used.
+func (d *generateDirective) expandedWords() []string {
+	vars := d.vars()
+	lookup := func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+	words := make([]string, 0, len(d.words))
+	for _, word := range d.words {
+		words = append(words, os.Expand(word, lookup))
+	}
+	return words
+}
+
+// inputFiles returns the file holding the directive along with the files matching its declared inputs.
+func (d *generateDirective) inputFiles() ([]string, error) {
+	files := []string{d.file}
+	for _, pattern := range d.inputs {
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(d.file), pattern))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", d.file, d.line-1, err)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// directiveScanner finds the go:generate directives in a file, keeping track of the aliases defined with -command and
+// the inputs declared for the next directive.
+type directiveScanner struct {
+	file       string
+	pkgName    string
+	aliases    map[string][]string
+	inputs     []string
+	directives []generateDirective
+}
+
+// declaredInputs returns the input globs that the given line declares, or nil if it doesn't declare any.
+func declaredInputs(line string) []string {
+	text, ok := strings.CutPrefix(line, inputsPrefix)
+	if !ok {
+		return nil
+	}
+	return strings.Fields(text)
+}
+
+func (s *directiveScanner) scanLine(lineNum int, line string) error {
+	text, ok := strings.CutPrefix(line, generatePrefix)
+	if !ok {
+		s.inputs = declaredInputs(line)
+		return nil
+	}
+	// Declared inputs only apply to the directive immediately after them.
+	defer func() { s.inputs = nil }()
+	return s.scanDirective(lineNum, text)
+}
+
+// scanDirective parses the text of a go:generate directive, after the prefix.
+func (s *directiveScanner) scanDirective(lineNum int, text string) error {
+	words, err := splitWords(text)
+	if err != nil || len(words) == 0 {
+		return fmt.Errorf("%s:%d: invalid go:generate directive: %w", s.file, lineNum, err)
+	}
+	if words[0] == commandFlag {
+		return s.defineAlias(lineNum, words[1:])
+	}
+	s.addDirective(lineNum, words)
+	return nil
+}
+
+// addDirective records a directive to run the given command, substituting any alias for the command's first word.
+func (s *directiveScanner) addDirective(lineNum int, words []string) {
+	if alias, ok := s.aliases[words[0]]; ok {
+		words = slices.Concat(alias, words[1:])
+	}
+	s.directives = append(s.directives, generateDirective{
+		file:    s.file,
+		line:    lineNum,
+		pkgName: s.pkgName,
+		words:   words,
+		inputs:  s.inputs,
+	})
+}
+
+// defineAlias records a -command directive, which defines a name for a command for the rest of the file. The arguments
+// are the name followed by the command.
+func (s *directiveScanner) defineAlias(lineNum int, args []string) error {
+	if len(args) <= 1 {
+		return fmt.Errorf("%s:%d: %s requires a name and a command", s.file, lineNum, commandFlag)
+	}
+	s.aliases[args[0]] = args[1:]
+	return nil
+}
+
+// scanFile returns the go:generate directives in the given file.
+func scanFile(file, pkgName string) ([]generateDirective, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := directiveScanner{file: file, pkgName: pkgName, aliases: map[string][]string{}}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if err = s.scanLine(lineNum, scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	return s.directives, scanner.Err()
+}
+
+// scanFiles returns the go:generate directives in the given files of the package, in order.
+func scanFiles(pkg magehelper.Package, pkgName string, files []string) (directives []generateDirective, err error) {
+	for _, file := range slices.Sorted(slices.Values(files)) {
+		fileDirectives, err := scanFile(filepath.Join(pkg.Dir, file), pkgName)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, fileDirectives...)
+	}
+	return directives, nil
+}
+
+// packageDirectives returns the go:generate directives in the package, including its tests, in the order that go
+// generate would run them.
+func packageDirectives(pkg magehelper.Package) ([]generateDirective, error) {
+	directives, err := scanFiles(pkg, pkg.Name, slices.Concat(pkg.GoFiles, pkg.TestGoFiles))
+	if err != nil {
+		return nil, err
+	}
+	xtestDirectives, err := scanFiles(pkg, pkg.Name+"_test", pkg.XTestGoFiles)
+	return append(directives, xtestDirectives...), err
+}
+
+// allDirectives returns the go:generate directives in all the loaded packages, ordered by package import path.
+func allDirectives() (directives []generateDirective, err error) {
+	pkgs := magehelper.LoadedPackages()
+	for _, path := range slices.Sorted(maps.Keys(pkgs)) {
+		pkgDirectives, err := packageDirectives(pkgs[path])
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, pkgDirectives...)
+	}
+	return directives, nil
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rkennedy/magehelper"
+)
+
+// directoryTimes returns the modification time of each file in the given directory, not counting subdirectories.
+func directoryTimes(dir string) (map[string]time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	times := make(map[string]time.Time, len(entries))
+	for _, entry := range slices.DeleteFunc(entries, fs.DirEntry.IsDir) {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		times[filepath.Join(dir, entry.Name())] = info.ModTime()
+	}
+	return times, nil
+}
+
+// changedFiles returns the files in the given directory that are new or modified since the directory had the given
+// modification times.
+func changedFiles(dir string, before map[string]time.Time) (changed []string, err error) {
+	after, err := directoryTimes(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range slices.Sorted(maps.Keys(after)) {
+		if modTime, ok := before[file]; !ok || !modTime.Equal(after[file]) {
+			changed = append(changed, file)
+		}
+	}
+	return changed, nil
+}
+
+// stampOutputs returns the outputs recorded in the given stamp file, which follow the directive on the first line. A
+// missing stamp file records no outputs.
+func stampOutputs(stamp string) ([]string, error) {
+	content, err := os.ReadFile(stamp)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	return lines[1:], nil
+}
+
+// exists reports whether the given file exists.
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// outputMissing reports whether any of the outputs recorded in the given stamp file has gone missing, in which case
+// the directive needs to run again even though its inputs haven't changed.
+func outputMissing(ctx context.Context, stamp string) (bool, error) {
+	outputs, err := stampOutputs(stamp)
+	if err != nil {
+		return false, err
+	}
+	idx := slices.IndexFunc(outputs, func(file string) bool { return !exists(file) })
+	if idx == -1 {
+		return false, nil
+	}
+	magehelper.LogDryRun(ctx, "output is out of date", "output", outputs[idx])
+	magehelper.LogExplain(ctx, "output does not exist", "output", outputs[idx])
+	return true, nil
+}
+
+// mergeOutputs combines the files that a directive just wrote with the outputs recorded from earlier runs that still
+// exist. Some generators don't rewrite files whose contents haven't changed, so a run doesn't necessarily modify every
+// output.
+func mergeOutputs(stamp string, changed []string) ([]string, error) {
+	previous, err := stampOutputs(stamp)
+	if err != nil {
+		return nil, err
+	}
+	outputs := slices.Concat(changed, slices.DeleteFunc(previous, func(file string) bool { return !exists(file) }))
+	slices.Sort(outputs)
+	return slices.Compact(outputs), nil
+}
@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+const (
+	// stampDirMode is the permission for creating the directory of stamp files.
+	stampDirMode fs.FileMode = 0o755
+	// stampFileMode is the permission for creating stamp files.
+	stampFileMode fs.FileMode = 0o644
+)
+
+// generateTool is a tool that go:generate directives may run, and which magehelper installs.
+type generateTool struct {
+	bin        string
+	importPath string
+}
+
+// GoGenerateTask is a Mage task that runs the go:generate directives in the project's packages.
+type GoGenerateTask struct {
+	stampDir string
+	tools    map[string]generateTool
+	modDir   string
+}
+
+var _ mg.Fn = &GoGenerateTask{}
+
+// GoGenerate returns a [mg.Fn] that runs the go:generate directives in all the packages loaded by
+// [magehelper.LoadDependencies], as go generate would. Package-level variables, such as $GOFILE and $GOPACKAGE, and
+// environment variables are expanded in each directive, and -command directives define aliases for the rest of their
+// files.
+//
+// Unlike go generate, the task only runs a directive when it's out of date. Each directive has a stamp file, kept in
+// the directory set by [GoGenerateTask.StampDir], and the directive runs when the file holding it is newer than its
+// stamp. The stamp also records the files in the directive's directory that the directive created or modified, and the
+// directive runs again if any of those outputs goes missing. A directive can declare additional inputs with a comment
+// on the line before it, listing file globs relative to the directive's directory:
+//
+//	//magehelper:inputs schema/*.json
+//	//go:generate go run ./cmd/genschema -out schema.go schema
+//
+// When a directive runs a tool registered with [GoGenerateTask.Tool], such as stringer, either by its name or with "go
+// run" or "go tool" and its import path, the task installs the tool at the version in go.mod and runs the installed
+// binary instead. The binary is also an input of the directive, so upgrading the tool reruns the directive.
+func GoGenerate() *GoGenerateTask {
+	return &GoGenerateTask{
+		stampDir: filepath.Join("bin", "generate"),
+		tools:    map[string]generateTool{},
+	}
+}
+
+// StampDir sets the directory where the task keeps the stamp files that record when each directive last ran. The
+// default is bin/generate. StampDir returns the GoGenerateTask.
+func (fn *GoGenerateTask) StampDir(dir string) *GoGenerateTask {
+	fn.stampDir = dir
+	return fn
+}
+
+// Tool registers a tool for directives to run. Directives that run the command with the given name, or that run the
+// given import path with "go run" or "go tool," will use the given binary instead, installed according to go.mod. Tool
+// returns the GoGenerateTask.
+func (fn *GoGenerateTask) Tool(name, bin, importPath string) *GoGenerateTask {
+	fn.tools[name] = generateTool{bin: bin, importPath: importPath}
+	return fn
+}
+
+// Stringer registers the given stringer binary for directives to use. Stringer returns the GoGenerateTask.
+func (fn *GoGenerateTask) Stringer(stringerBin string) *GoGenerateTask {
+	return fn.Tool("stringer", stringerBin, stringerImport)
+}
+
+// Mockgen registers the given mockgen binary for directives to use. Mockgen returns the GoGenerateTask.
+func (fn *GoGenerateTask) Mockgen(mockgenBin string) *GoGenerateTask {
+	return fn.Tool("mockgen", mockgenBin, mockgenImport)
+}
+
+// Goimports registers the given goimports binary for directives to use. Goimports returns the GoGenerateTask.
+func (fn *GoGenerateTask) Goimports(goimportsBin string) *GoGenerateTask {
+	return fn.Tool("goimports", goimportsBin, goimportsImport)
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which versions of the
+// registered tools this project uses. ModDir returns the GoGenerateTask.
+func (fn *GoGenerateTask) ModDir(dir string) *GoGenerateTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (*GoGenerateTask) Name() string {
+	return "Go generate"
+}
+
+// ID implements [mg.Fn].
+func (fn *GoGenerateTask) ID() string {
+	return fmt.Sprintf("magehelper go generate %s", fn.stampDir)
+}
+
+// Run implements [mg.Fn].
+func (fn *GoGenerateTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *GoGenerateTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.LoadDependencies)
+	directives, err := allDirectives()
+	if err != nil {
+		return err
+	}
+	magehelper.Deps(ctx, fn.installs(directives)...)
+
+	ran, err := fn.runDirectives(ctx, directives)
+	if err != nil || !ran {
+		return err
+	}
+	// The directives might have added files or packages.
+	return magehelper.ReloadPackages(ctx)
+}
+
+// runDirectives runs the out-of-date directives in order, stopping at the first failure. It reports whether any of
+// them ran.
+func (fn *GoGenerateTask) runDirectives(ctx context.Context, directives []generateDirective) (ran bool, err error) {
+	for _, d := range directives {
+		directiveRan, err := fn.runDirective(ctx, d)
+		if err != nil {
+			return ran, err
+		}
+		ran = ran || directiveRan
+	}
+	return ran, nil
+}
+
+// toolWords returns the number of leading words of the command that run the given tool, or 0 if the command doesn't
+// run it. The command can name the tool, or it can run the tool's import path, optionally with a version, with "go
+// run" or "go tool."
+func toolWords(words []string, name string, tool generateTool) int {
+	const goToolWords = 3
+	switch {
+	case words[0] == name:
+		return 1
+	case len(words) < goToolWords || words[0] != "go" || (words[1] != "run" && words[1] != "tool"):
+		return 0
+	case strings.Split(words[2], "@")[0] == tool.importPath:
+		return goToolWords
+	default:
+		return 0
+	}
+}
+
+// substituteTool replaces the command's reference to a registered tool with the tool's binary, and it returns the tool
+// that it substituted, if any. A plain "go" command is replaced with the go command that Mage uses.
+func (fn *GoGenerateTask) substituteTool(words []string) ([]string, *generateTool) {
+	for _, name := range slices.Sorted(maps.Keys(fn.tools)) {
+		tool := fn.tools[name]
+		if n := toolWords(words, name, tool); n > 0 {
+			// The command runs in the directive's directory, so the binary's name needs to be absolute.
+			bin, _ := filepath.Abs(tool.bin)
+			return slices.Concat([]string{bin}, words[n:]), &tool
+		}
+	}
+	if words[0] == "go" {
+		return slices.Concat([]string{mg.GoCmd()}, words[1:]), nil
+	}
+	return words, nil
+}
+
+// installs returns the tasks that install the registered tools that the directives use.
+func (fn *GoGenerateTask) installs(directives []generateDirective) (installs []any) {
+	used := map[generateTool]bool{}
+	for _, d := range directives {
+		if _, tool := fn.substituteTool(d.expandedWords()); tool != nil && !used[*tool] {
+			used[*tool] = true
+			installs = append(installs, magehelper.Install(tool.bin, tool.importPath).ModDir(fn.modDir))
+		}
+	}
+	return installs
+}
+
+// stampFile returns the name of the file that records when the directive last ran.
+func (fn *GoGenerateTask) stampFile(d generateDirective) string {
+	sum := sha256.Sum256([]byte(d.String()))
+	return filepath.Join(fn.stampDir, hex.EncodeToString(sum[:])+".stamp")
+}
+
+// touchStamp records that the directive ran and which files it wrote. In dry-run mode, it does nothing, so the
+// directive will still be out of date next time.
+func touchStamp(stamp string, d generateDirective, changed []string) error {
+	if magehelper.DryRun() {
+		return nil
+	}
+	outputs, err := mergeOutputs(stamp, changed)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(stamp), stampDirMode); err != nil {
+		return err
+	}
+	return os.WriteFile(stamp, []byte(strings.Join(slices.Concat([]string{d.String()}, outputs), "\n")+"\n"),
+		stampFileMode)
+}
+
+// directiveStale reports whether the directive needs to run, and it returns the name of the directive's stamp file.
+// The directive's inputs are the file holding it, its declared inputs, and the tool it runs, if any. The directive
+// also needs to run if any of the outputs recorded from its last run is missing.
+func (fn *GoGenerateTask) directiveStale(
+	ctx context.Context,
+	d generateDirective,
+	tool *generateTool,
+) (stamp string, stale bool, err error) {
+	inputs, err := d.inputFiles()
+	if err != nil {
+		return "", false, err
+	}
+	if tool != nil {
+		inputs = append(inputs, tool.bin)
+	}
+	stamp = fn.stampFile(d)
+	if stale, err = outputMissing(ctx, stamp); err != nil || stale {
+		return stamp, stale, err
+	}
+	stale, err = magehelper.Stale(ctx, stamp, inputs...)
+	return stamp, stale, err
+}
+
+// runDirective runs the directive's command if the directive is out of date, and it reports whether it ran.
+func (fn *GoGenerateTask) runDirective(ctx context.Context, d generateDirective) (bool, error) {
+	words, tool := fn.substituteTool(d.expandedWords())
+	stamp, stale, err := fn.directiveStale(ctx, d, tool)
+	if err != nil || !stale {
+		return false, err
+	}
+	changed, err := runCommand(ctx, d, words)
+	if err != nil {
+		return false, err
+	}
+	return true, touchStamp(stamp, d, changed)
+}
+
+// runCommand runs the directive's command, with expanded words, in the directive's directory. It returns the files in
+// that directory that the command created or modified, which are the directive's outputs.
+func runCommand(ctx context.Context, d generateDirective, words []string) ([]string, error) {
+	cmd := exec.Command(words[0], words[1:]...)
+	cmd.Dir = filepath.Dir(d.file)
+	cmd.Env = append(os.Environ(), d.env()...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	before, err := directoryTimes(cmd.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = magehelper.RunCmd(ctx, cmd); err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", d.file, d.line, err)
+	}
+	return changedFiles(cmd.Dir, before)
+}
@@ -0,0 +1,68 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var generateDir = filepath.Join("examples", "generate")
+
+var _ = Describe("GoGenerate", Ordered, func() {
+	BeforeAll(func() {
+		removeAfterAll(
+			filepath.Join(generateDir, "pill_string.go"),
+			filepath.Join(generateDir, "names_gen.go"),
+			filepath.Join(generateDir, "bin", "generate"),
+		)
+		By("running the go:generate directives")
+		invokeExample(generateDir, "generate")
+	})
+
+	It("runs every directive", func() {
+		Expect(filepath.Join(generateDir, "pill_string.go")).To(BeAnExistingFile())
+		Expect(os.ReadFile(filepath.Join(generateDir, "names_gen.go"))).To(SatisfyAll(
+			ContainSubstring("package main"),
+			ContainSubstring(`"alpha", "beta"`),
+		))
+	})
+
+	It("runs nothing when everything is up to date", func() {
+		Expect(dryRunExample(generateDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("runs directives whose declared inputs changed", func() {
+		touchLater(filepath.Join(generateDir, "names.txt"))
+
+		output := dryRunExample(generateDir, "generate")
+		Expect(output).To(MatchRegexp(`would run.*gennames`))
+		Expect(output).NotTo(MatchRegexp(`would run.*stringer`))
+	})
+
+	It("runs directives whose outputs are missing", func() {
+		Expect(os.Remove(filepath.Join(generateDir, "pill_string.go"))).To(Succeed())
+
+		output := dryRunExample(generateDir, "generate")
+		Expect(output).To(MatchRegexp(`would run.*stringer`))
+		Expect(output).NotTo(MatchRegexp(`would run.*gennames`))
+	})
+})
+
+var _ = Describe("Codegen", Ordered, func() {
+	BeforeAll(func() {
+		removeAfterAll(filepath.Join(generateDir, "colors", "color_string.go"))
+		By("running the generator")
+		invokeExample(generateDir, "colors")
+	})
+
+	It("generates the outputs", func() {
+		Expect(os.ReadFile(filepath.Join(generateDir, "colors", "color_string.go"))).To(
+			ContainSubstring("func (i Color) String() string"))
+	})
+
+	It("doesn't run when outputs are up to date", func() {
+		Expect(dryRunExample(generateDir, "colors")).NotTo(ContainSubstring("would run"))
+	})
+})
@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/internal/golangcilint"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// defaultGolangciLintTimeout is the timeout for version 1 of golangci-lint when neither the task nor the
+	// configuration file sets one. That version's own default of one minute is too short for many projects.
+	defaultGolangciLintTimeout = 5 * time.Minute
+	// golangciLintV1 is the major version of golangci-lint from before version 2 changed the command-line flags.
+	golangciLintV1 = "v1"
+	// stdoutPath is the path that tells golangci-lint version 2 to write a format to standard output.
+	stdoutPath = "stdout"
+)
+
+// GolangciLintTask is a Mage task that runs golangci-lint.
+type GolangciLintTask struct {
+	bin        string
+	version    string
+	config     string
+	formats    []string
+	newFromRev string
+	fix        bool
+	tags       []string
+	timeout    time.Duration
+	modDir     string
+}
+
+var _ mg.Fn = &GolangciLintTask{}
+
+// GolangciLint returns a [mg.Fn] that runs golangci-lint on all the packages loaded by [magehelper.LoadDependencies].
+// It first installs the given version of golangci-lint at the given location with [magehelper.InstallGolangciLint].
+// The version may be empty to use the version pinned in go.mod or the latest release for the configuration file.
+//
+// The task works with both major versions of golangci-lint, choosing the command-line flags for the version it
+// installed. Unless [GolangciLintTask.Timeout] sets a timeout, the task uses the run.timeout setting from the
+// configuration file. If that's not set either, version 1 gets a timeout of five minutes, and version 2 runs without a
+// timeout, which is its default.
+func GolangciLint(bin, version string) *GolangciLintTask {
+	return &GolangciLintTask{
+		bin:     bin,
+		version: version,
+	}
+}
+
+// Config sets the configuration file. By default, golangci-lint looks for one in the current directory and its
+// parents, and the task reads .golangci.yml, .golangci.yaml, or .golangci.json from the current directory for its
+// timeout. Config returns the GolangciLintTask.
+func (fn *GolangciLintTask) Config(config string) *GolangciLintTask {
+	fn.config = config
+	return fn
+}
+
+// Formats sets the output formats, such as colored-line-number or checkstyle:report.xml. Each format may name a file
+// to write after a colon; otherwise, it goes to standard output. The format names must be ones that the installed
+// version knows, such as text instead of colored-line-number for version 2. Formats returns the GolangciLintTask.
+func (fn *GolangciLintTask) Formats(formats ...string) *GolangciLintTask {
+	fn.formats = formats
+	return fn
+}
+
+// NewFromRev limits reports to problems introduced since the given Git revision. NewFromRev returns the
+// GolangciLintTask.
+func (fn *GolangciLintTask) NewFromRev(rev string) *GolangciLintTask {
+	fn.newFromRev = rev
+	return fn
+}
+
+// Fix makes golangci-lint fix the problems it finds, for the linters that support it. Fix returns the
+// GolangciLintTask.
+func (fn *GolangciLintTask) Fix() *GolangciLintTask {
+	fn.fix = true
+	return fn
+}
+
+// Tags sets the build tags for loading packages. Tags returns the GolangciLintTask.
+func (fn *GolangciLintTask) Tags(tags ...string) *GolangciLintTask {
+	fn.tags = tags
+	return fn
+}
+
+// Timeout sets how long golangci-lint may run, overriding the configuration file. Timeout returns the
+// GolangciLintTask.
+func (fn *GolangciLintTask) Timeout(timeout time.Duration) *GolangciLintTask {
+	fn.timeout = timeout
+	return fn
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that pins the version of golangci-lint when
+// the version is empty. ModDir returns the GolangciLintTask.
+func (fn *GolangciLintTask) ModDir(dir string) *GolangciLintTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (*GolangciLintTask) Name() string {
+	return "Golangci-lint"
+}
+
+// ID implements [mg.Fn].
+func (fn *GolangciLintTask) ID() string {
+	return fmt.Sprintf("magehelper run %s(%s)", fn.bin, strings.Join([]string{
+		fn.version,
+		fn.config,
+		strings.Join(fn.formats, listSeparator),
+		fn.newFromRev,
+		strconv.FormatBool(fn.fix),
+		strings.Join(fn.tags, listSeparator),
+		fn.timeout.String(),
+		fn.modDir,
+	}, " "))
+}
+
+// Run implements [mg.Fn].
+func (fn *GolangciLintTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *GolangciLintTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx,
+		magehelper.InstallGolangciLint(fn.bin, fn.version).ModDir(fn.modDir),
+		magehelper.LoadDependencies,
+	)
+	major, err := fn.majorVersion(ctx)
+	if err != nil {
+		return err
+	}
+	timeoutArgs, err := fn.timeoutArgs(major)
+	if err != nil {
+		return err
+	}
+	args := slices.Concat([]string{"run"}, timeoutArgs, fn.optionalArgs(major), packageDirs())
+	return magehelper.RunV(ctx, fn.bin, args...)
+}
+
+// majorVersion returns the major version of the installed golangci-lint, such as v2. In dry-run mode, the binary might
+// not be installed yet, so it falls back to the major version of the version that the task would install. It's an
+// error if that doesn't determine a major version, such as for "latest."
+func (fn *GolangciLintTask) majorVersion(ctx context.Context) (string, error) {
+	version, err := golangcilint.InstalledVersion(fn.bin)
+	if err != nil && magehelper.DryRun() {
+		magehelper.LogDryRun(ctx, "could not get installed version", "binary", fn.bin, "error", err)
+		version, err = fn.wantedVersion(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	major := semver.Major(version)
+	if major == "" {
+		return "", fmt.Errorf("cannot determine the major version of golangci-lint from version %q", version)
+	}
+	return major, nil
+}
+
+// wantedVersion returns the version that the task installs: the task's own version, or else the one that go.mod pins
+// or that the configuration file calls for.
+func (fn *GolangciLintTask) wantedVersion(ctx context.Context) (string, error) {
+	if fn.version != "" {
+		return fn.version, nil
+	}
+	return golangcilint.DesiredVersion(magehelper.TaskLogger(ctx), fn.modDir)
+}
+
+// timeoutArgs returns the command-line arguments that set the timeout for the given major version, if any.
+func (fn *GolangciLintTask) timeoutArgs(major string) ([]string, error) {
+	timeout, err := fn.effectiveTimeout()
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 && major == golangciLintV1 {
+		timeout = defaultGolangciLintTimeout
+	}
+	if timeout == 0 {
+		return nil, nil
+	}
+	return []string{"--timeout", timeout.String()}, nil
+}
+
+// optionalArgs returns the command-line arguments for the options that have been set.
+func (fn *GolangciLintTask) optionalArgs(major string) (args []string) {
+	options := []struct{ flag, value string }{
+		{"--config", fn.config},
+		{"--new-from-rev", fn.newFromRev},
+		{"--build-tags", strings.Join(fn.tags, listSeparator)},
+	}
+	for _, opt := range options {
+		if opt.value != "" {
+			args = append(args, opt.flag, opt.value)
+		}
+	}
+	if fn.fix {
+		args = append(args, "--fix")
+	}
+	return append(args, fn.formatArgs(major)...)
+}
+
+// formatArgs returns the command-line arguments that select the output formats for the given major version. Version 1
+// takes all the formats in one flag, and version 2 has a flag for the path of each format.
+func (fn *GolangciLintTask) formatArgs(major string) (args []string) {
+	if len(fn.formats) == 0 {
+		return nil
+	}
+	if major == golangciLintV1 {
+		return []string{"--out-format", strings.Join(fn.formats, listSeparator)}
+	}
+	for _, format := range fn.formats {
+		name, path, _ := strings.Cut(format, ":")
+		args = append(args, "--output."+name+".path", cmp.Or(path, stdoutPath))
+	}
+	return args
+}
+
+// configFile returns the configuration file that the task reads, or the empty string if there isn't one.
+func (fn *GolangciLintTask) configFile() string {
+	return cmp.Or(fn.config, golangcilint.ConfigFile())
+}
+
+// effectiveTimeout returns the timeout set for the task, or else the one in the configuration file, or else zero.
+func (fn *GolangciLintTask) effectiveTimeout() (time.Duration, error) {
+	if fn.timeout != 0 {
+		return fn.timeout, nil
+	}
+	if file := fn.configFile(); file != "" {
+		return configuredTimeout(file)
+	}
+	return 0, nil
+}
+
+// configuredTimeout returns the timeout from the given configuration file, or zero if the file doesn't set one.
+func configuredTimeout(file string) (time.Duration, error) {
+	config, err := golangcilint.ReadConfig(file)
+	if err != nil || config.Run.Timeout == "" {
+		return 0, err
+	}
+	return time.ParseDuration(config.Run.Timeout)
+}
+
+// packageDirs returns the directories of the loaded packages as relative paths that golangci-lint accepts.
+func packageDirs() (dirs []string) {
+	pkgs := magehelper.LoadedPackages()
+	for _, importPath := range slices.Sorted(maps.Keys(pkgs)) {
+		rel := pkgs[importPath].RelPath()
+		dirs = append(dirs, strings.TrimSuffix("./"+filepath.ToSlash(rel), "/."))
+	}
+	return dirs
+}
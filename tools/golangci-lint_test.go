@@ -0,0 +1,217 @@
+package tools_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+// fakeGolangciLint reports the given version the way golangci-lint does, and otherwise it records its arguments in
+// args.txt beside itself, one per line.
+const fakeGolangciLint = `#!/bin/sh
+if [ "$1" = --version ]; then
+	echo "golangci-lint has version %s built with go1.25.0 from 0123abcd on 2025-01-01T00:00:00Z"
+	exit
+fi
+printf '%%s\n' "$@" > "$(dirname "$0")/args.txt"
+`
+
+// installFakeGolangciLint writes a fake golangci-lint that reports the given version at the given location. It
+// records the checksum the way the installer does, so the fake counts as installed and nothing gets downloaded.
+func installFakeGolangciLint(bin, version string) {
+	GinkgoHelper()
+	if runtime.GOOS == "windows" {
+		Skip("the fake golangci-lint is a shell script")
+	}
+	script := []byte(fmt.Sprintf(fakeGolangciLint, version))
+	Expect(os.MkdirAll(filepath.Dir(bin), 0o755)).To(Succeed())
+	Expect(os.WriteFile(bin, script, 0o755)).To(Succeed())
+	sum := sha256.Sum256(script)
+	Expect(os.WriteFile(bin+".sha256", []byte(hex.EncodeToString(sum[:])+"\n"), 0o644)).To(Succeed())
+}
+
+// golangciLintArgs returns the arguments that the fake golangci-lint at the given location last received.
+func golangciLintArgs(bin string) []string {
+	GinkgoHelper()
+	args, err := os.ReadFile(filepath.Join(filepath.Dir(bin), "args.txt"))
+	Expect(err).NotTo(HaveOccurred())
+	return strings.Split(strings.TrimSuffix(string(args), "\n"), "\n")
+}
+
+var _ = Describe("GolangciLint", func() {
+	var bin string
+
+	BeforeEach(func() {
+		bin = filepath.Join(GinkgoT().TempDir(), "golangci-lint")
+		installFakeGolangciLint(bin, "v1.2.3")
+	})
+
+	writeConfig := func(content string) string {
+		GinkgoHelper()
+		config := filepath.Join(filepath.Dir(bin), ".golangci.yml")
+		Expect(os.WriteFile(config, []byte(content), 0o644)).To(Succeed())
+		return config
+	}
+
+	// lint runs the task and returns the arguments it passed to golangci-lint, up to the list of packages, which the
+	// example project's spec checks.
+	lint := func(ctx context.Context, task *tools.GolangciLintTask) []string {
+		GinkgoHelper()
+		Expect(task.Run(ctx)).To(Succeed())
+		args := golangciLintArgs(bin)
+		return args[:len(args)-1]
+	}
+
+	It("sets the default timeout", func(ctx context.Context) {
+		Expect(lint(ctx, tools.GolangciLint(bin, "v1.2.3"))).To(Equal([]string{"run", "--timeout", "5m0s"}))
+	})
+
+	It("passes the options that are set", func(ctx context.Context) {
+		config := writeConfig("linters:\n  enable:\n    - revive\n")
+		task := tools.GolangciLint(bin, "v1.2.3").
+			Config(config).
+			Formats("colored-line-number", "checkstyle:report.xml").
+			NewFromRev("HEAD~").
+			Fix().
+			Tags("integration", "slow").
+			Timeout(time.Minute)
+		Expect(lint(ctx, task)).To(Equal([]string{
+			"run", "--timeout", "1m0s",
+			"--config", config,
+			"--new-from-rev", "HEAD~",
+			"--build-tags", "integration,slow",
+			"--fix",
+			"--out-format", "colored-line-number,checkstyle:report.xml",
+		}))
+	})
+
+	It("sets no timeout for version 2 by default", func(ctx context.Context) {
+		installFakeGolangciLint(bin, "v2.1.6")
+		Expect(lint(ctx, tools.GolangciLint(bin, "v2.1.6"))).To(Equal([]string{"run"}))
+	})
+
+	It("uses the configuration file's timeout for version 2", func(ctx context.Context) {
+		installFakeGolangciLint(bin, "v2.1.6")
+		config := writeConfig("version: \"2\"\nrun:\n  timeout: 3m\n")
+		Expect(lint(ctx, tools.GolangciLint(bin, "v2.1.6").Config(config))).To(
+			Equal([]string{"run", "--timeout", "3m0s", "--config", config}))
+	})
+
+	It("passes a path for each of version 2's output formats", func(ctx context.Context) {
+		installFakeGolangciLint(bin, "v2.1.6")
+		Expect(lint(ctx, tools.GolangciLint(bin, "v2.1.6").Formats("text", "checkstyle:report.xml"))).To(
+			Equal([]string{
+				"run",
+				"--output.text.path", "stdout",
+				"--output.checkstyle.path", "report.xml",
+			}))
+	})
+
+	It("uses the configuration file's timeout", func(ctx context.Context) {
+		config := writeConfig("run:\n  timeout: 3m\n")
+		Expect(lint(ctx, tools.GolangciLint(bin, "v1.2.3").Config(config))).To(
+			ContainElements("--timeout", "3m0s"))
+	})
+
+	It("prefers the task's timeout to the configuration file's", func(ctx context.Context) {
+		config := writeConfig("run:\n  timeout: 3m\n")
+		Expect(lint(ctx, tools.GolangciLint(bin, "v1.2.3").Config(config).Timeout(time.Minute))).To(
+			ContainElements("--timeout", "1m0s"))
+	})
+
+	It("reports an invalid timeout in the configuration file", func(ctx context.Context) {
+		config := writeConfig("run:\n  timeout: soon\n")
+		Expect(tools.GolangciLint(bin, "v1.2.3").Config(config).Run(ctx)).To(
+			MatchError(ContainSubstring(`invalid duration "soon"`)))
+	})
+})
+
+var _ = Describe("GolangciLint in dry-run mode", func() {
+	var (
+		bin string
+		log bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		bin = filepath.Join(GinkgoT().TempDir(), "golangci-lint")
+		// The binary isn't installed, so the task has to work out which version it would install. The fake Github
+		// API reports v2.1.6 as both the latest release and the one with that tag, and nothing gets downloaded.
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			archive := fmt.Sprintf("golangci-lint-2.1.6-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+			Expect(json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v2.1.6",
+				"assets":   []map[string]any{{"name": archive, "browser_download_url": "http://example.invalid/"}},
+			})).To(Succeed())
+		}))
+		DeferCleanup(server.Close)
+		GinkgoT().Setenv(magehelper.GithubAPIURLEnv, server.URL)
+
+		log.Reset()
+		magehelper.SetLogger(slog.New(slog.NewTextHandler(&log, nil)))
+		DeferCleanup(magehelper.SetLogger, (*slog.Logger)(nil))
+		magehelper.SetDryRun(true)
+		DeferCleanup(magehelper.SetDryRun, false)
+	})
+
+	It("uses the flags of the version it would install", func(ctx context.Context) {
+		Expect(tools.GolangciLint(bin, "v2.1.6").Formats("text").Run(ctx)).To(Succeed())
+		Expect(log.String()).To(ContainSubstring("--output.text.path stdout"))
+	})
+
+	It("reports a version that doesn't determine the major version", func(ctx context.Context) {
+		Expect(tools.GolangciLint(bin, "latest").Run(ctx)).To(
+			MatchError(ContainSubstring(`cannot determine the major version of golangci-lint from version "latest"`)))
+	})
+})
+
+var _ = Describe("GolangciLint ID", func() {
+	It("distinguishes tasks with different settings", func() {
+		bin := filepath.Join("bin", "golangci-lint")
+		ids := []string{
+			tools.GolangciLint(bin, "").ID(),
+			tools.GolangciLint(bin, "v2.1.6").ID(),
+			tools.GolangciLint(bin, "").Config(".golangci.yml").ID(),
+			tools.GolangciLint(bin, "").Formats("text").ID(),
+			tools.GolangciLint(bin, "").NewFromRev("HEAD~").ID(),
+			tools.GolangciLint(bin, "").Fix().ID(),
+			tools.GolangciLint(bin, "").Tags("integration").ID(),
+			tools.GolangciLint(bin, "").Timeout(time.Minute).ID(),
+			tools.GolangciLint(bin, "").ModDir("tools").ID(),
+		}
+		Expect(slices.Compact(slices.Sorted(slices.Values(ids)))).To(HaveLen(len(ids)))
+	})
+})
+
+var _ = Describe("GolangciLint example", Ordered, func() {
+	golangciLintDir := filepath.Join("examples", "golangci-lint")
+	bin := filepath.Join(golangciLintDir, "bin", "golangci-lint")
+
+	BeforeAll(func() {
+		removeAfterAll(filepath.Join(golangciLintDir, "bin"))
+		installFakeGolangciLint(bin, "v1.64.8")
+	})
+
+	It("lints every package", func() {
+		invokeExample(golangciLintDir, "lint")
+		// The root package's directory is ".", not "./.".
+		Expect(golangciLintArgs(bin)).To(HaveExactElements("run", "--timeout", "5m0s", ".", "./greet"))
+	})
+})
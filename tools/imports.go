@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/magefile/mage/mg"
-	"github.com/magefile/mage/sh"
 	"github.com/rkennedy/magehelper"
 )
 
@@ -25,8 +23,12 @@ func (fn *importTask) Name() string {
 }
 
 func (fn *importTask) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx, magehelper.Install(fn.goimportsBin, goimportsImport).ModDir(fn.modDir))
-	return sh.RunV(fn.goimportsBin, "-w", "-l", ".")
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *importTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.Install(fn.goimportsBin, goimportsImport).ModDir(fn.modDir))
+	return magehelper.RunV(ctx, fn.goimportsBin, "-w", "-l", ".")
 }
 
 func (fn *importTask) ModDir(dir string) magehelper.InstallTask {
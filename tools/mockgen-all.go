@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/iters"
+)
+
+// MockgenAllTask is a Mage task that generates mock types for every directory in the project that has a mockgen.yaml
+// file.
+type MockgenAllTask struct {
+	mockgenBin  string
+	modDir      string
+	concurrency int
+	manifestDir string
+}
+
+var _ mg.Fn = &MockgenAllTask{}
+
+// MockgenAll returns a [mg.Fn] that finds every mockgen.yaml file among the directories of the project's packages, as
+// loaded by [magehelper.LoadDependencies], and generates mocks for each of them as [Mockgen] would. Adding mocks to a
+// new package only requires adding a mockgen.yaml file there; the magefile doesn't need to change.
+func MockgenAll(mockgenBin string) *MockgenAllTask {
+	return &MockgenAllTask{mockgenBin: mockgenBin, manifestDir: defaultManifestDir}
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of mockgen this
+// project uses, as with [MockgenTask.ModDir]. ModDir returns the MockgenAllTask.
+func (fn *MockgenAllTask) ModDir(dir string) *MockgenAllTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Concurrency sets the maximum number of mockgen processes that each directory's task runs at once, as with
+// [MockgenTask.Concurrency]. Concurrency returns the MockgenAllTask.
+func (fn *MockgenAllTask) Concurrency(n int) *MockgenAllTask {
+	fn.concurrency = n
+	return fn
+}
+
+// ManifestDir sets the directory where each directory's task keeps its manifest of generated files, as with
+// [MockgenTask.ManifestDir]. ManifestDir returns the MockgenAllTask.
+func (fn *MockgenAllTask) ManifestDir(dir string) *MockgenAllTask {
+	fn.manifestDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (*MockgenAllTask) Name() string {
+	return "Mockgen all directories"
+}
+
+// ID implements [mg.Fn].
+func (fn *MockgenAllTask) ID() string {
+	return "magehelper mockgen all " + fn.mockgenBin
+}
+
+// Run implements [mg.Fn].
+func (fn *MockgenAllTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *MockgenAllTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.LoadDependencies)
+	dirs, err := mockgenDirs()
+	if err != nil {
+		return err
+	}
+	magehelper.Deps(ctx, slices.Collect(iters.SliceTransform(slices.Values(dirs), func(dir string) any {
+		return Mockgen(fn.mockgenBin, dir).ModDir(fn.modDir).Concurrency(fn.concurrency).ManifestDir(fn.manifestDir)
+	}))...)
+	return nil
+}
+
+// mockgenDirs returns the sorted list of package directories that contain mockgen.yaml, relative to the module root.
+func mockgenDirs() ([]string, error) {
+	var dirs []string
+	pkgs := maps.Values(magehelper.LoadedPackages())
+	for dir := range iters.SliceTransform(pkgs, func(pkg magehelper.Package) string {
+		return pkg.RelPath()
+	}) {
+		_, err := os.Stat(filepath.Join(dir, mockgenConfig))
+		switch {
+		case err == nil:
+			dirs = append(dirs, dir)
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		default:
+			// No mocks requested for this package.
+		}
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs), nil
+}
@@ -0,0 +1,331 @@
+package tools
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Each directory that _uses_ mocks will have a mockgen.yaml file listing all the packages and types that it needs mocks
+// from.
+type mockgenRec struct {
+	External            bool              `yaml:"external"`
+	Types               []string          `yaml:"types"`
+	Destination         string            `yaml:"destination"`
+	MockNames           map[string]string `yaml:"mock_names"`
+	SelfPackage         string            `yaml:"self_package"`
+	BuildConstraint     string            `yaml:"build_constraint"`
+	Typed               bool              `yaml:"typed"`
+	CopyrightFile       string            `yaml:"copyright_file"`
+	WritePackageComment *bool             `yaml:"write_package_comment"`
+	Source              string            `yaml:"source"`
+	AuxFiles            map[string]string `yaml:"aux_files"`
+	PackageDir          string            `yaml:"package_dir"`
+}
+
+// mockgenKeys lists the keys that a mockgen.yaml entry may have, which are the yaml tags of [mockgenRec].
+var mockgenKeys = yamlKeys(reflect.TypeFor[mockgenRec]())
+
+// yamlKeys returns the keys that the yaml tags of the given struct type's fields declare.
+func yamlKeys(t reflect.Type) []string {
+	keys := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+type mockDefinition struct {
+	mockgenRec
+	SourcePackage string
+	// Dir is the directory holding the mockgen.yaml file that this definition came from. Relative file names in the
+	// definition are relative to this directory.
+	Dir string
+	// Output is the full name of the generated file, once [MockgenTask.resolveOutputs] has determined it.
+	Output string
+}
+
+func (def *mockDefinition) OutputPackageName(basePackage string) string {
+	if def.PackageDir != "" {
+		return filepath.Base(def.PackageDir)
+	}
+	if def.External {
+		return basePackage + "_test"
+	}
+	return basePackage
+}
+
+// outputDir returns the directory where the generated file goes. That's the directory holding mockgen.yaml, unless the
+// definition asks for a shared mock package.
+func (def *mockDefinition) outputDir() string {
+	return filepath.Join(def.Dir, def.PackageDir)
+}
+
+// outputFile returns the full name of the generated file. If the definition doesn't specify a destination, then the
+// name is based on the given name of the mocked package or source file. Mocks in a shared package go in an ordinary
+// Go file, and all others go in a test file.
+func (def *mockDefinition) outputFile(name string) string {
+	format := mockFileFormat
+	if def.PackageDir != "" {
+		format = sharedMockFileFormat
+	}
+	return filepath.Join(def.outputDir(), cmp.Or(def.Destination, fmt.Sprintf(format, name)))
+}
+
+// copyrightFile returns the path of the configured copyright file, or the empty string if there isn't one.
+func (def *mockDefinition) copyrightFile() string {
+	if def.CopyrightFile == "" {
+		return ""
+	}
+	return filepath.Join(def.Dir, def.CopyrightFile)
+}
+
+// sourceFile returns the path of the source file for source mode, or the empty string for package mode.
+func (def *mockDefinition) sourceFile() string {
+	if def.Source == "" {
+		return ""
+	}
+	return filepath.Join(def.Dir, def.Source)
+}
+
+// auxFiles formats the aux_files map as mockgen's -aux_files option expects.
+func (def *mockDefinition) auxFiles() string {
+	files := make([]string, 0, len(def.AuxFiles))
+	for _, pkg := range slices.Sorted(maps.Keys(def.AuxFiles)) {
+		files = append(files, pkg+"="+filepath.Join(def.Dir, def.AuxFiles[pkg]))
+	}
+	return strings.Join(files, listSeparator)
+}
+
+// mockNames formats the mock_names map as mockgen's -mock_names option expects.
+func (def *mockDefinition) mockNames() string {
+	names := make([]string, 0, len(def.MockNames))
+	for _, typeName := range slices.Sorted(maps.Keys(def.MockNames)) {
+		names = append(names, typeName+"="+def.MockNames[typeName])
+	}
+	return strings.Join(names, listSeparator)
+}
+
+// appendFlag appends the flag and its value to args, unless the value is empty.
+func appendFlag(args []string, flag, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, flag, value)
+}
+
+// listSeparator separates the items of mockgen's list-valued options.
+const listSeparator = ","
+
+// args returns the command-line options for mockgen to generate this definition's mocks into the given file.
+func (def *mockDefinition) args(outFileName, basePackage string) []string {
+	args := append([]string{
+		"-destination", outFileName,
+		"-package", def.OutputPackageName(basePackage),
+	}, def.optionalArgs()...)
+	if def.Source != "" {
+		args = appendFlag(args, "-aux_files", def.auxFiles())
+		return append(args, "-source", def.sourceFile())
+	}
+	return append(args, def.SourcePackage, strings.Join(def.Types, listSeparator))
+}
+
+// optionalArgs returns the command-line options for the definition's optional settings.
+func (def *mockDefinition) optionalArgs() (args []string) {
+	args = appendFlag(args, "-self_package", def.SelfPackage)
+	args = appendFlag(args, "-mock_names", def.mockNames())
+	args = appendFlag(args, "-build_constraint", def.BuildConstraint)
+	args = appendFlag(args, "-copyright_file", def.copyrightFile())
+	if def.Typed {
+		args = append(args, "-typed")
+	}
+	if def.WritePackageComment != nil {
+		args = append(args, "-write_package_comment="+strconv.FormatBool(*def.WritePackageComment))
+	}
+	return args
+}
+
+// inputs returns the files, besides mockgen.yaml and the mocked package's own files, that the generated file depends
+// on. In source mode, that includes the source file and the auxiliary files.
+func (def *mockDefinition) inputs() []string {
+	files := []string{def.copyrightFile(), def.sourceFile()}
+	for _, file := range def.AuxFiles {
+		files = append(files, filepath.Join(def.Dir, file))
+	}
+	return slices.DeleteFunc(files, func(s string) bool { return s == "" })
+}
+
+func (def *mockDefinition) validateTypes() (errs []error) {
+	if def.Source != "" {
+		return def.validateSourceMode()
+	}
+	if len(def.Types) == 0 {
+		errs = append(errs, errors.New("types: at least one type is required"))
+	}
+	if len(def.AuxFiles) > 0 {
+		errs = append(errs, errors.New("aux_files: only allowed with source"))
+	}
+	for _, typeName := range slices.Sorted(maps.Keys(def.MockNames)) {
+		if !slices.Contains(def.Types, typeName) {
+			errs = append(errs, fmt.Errorf("mock_names: %s is not listed in types", typeName))
+		}
+	}
+	return errs
+}
+
+// validateSourceMode checks the options that matter in source mode, where mockgen mocks every interface in the source
+// file instead of a list of types.
+func (def *mockDefinition) validateSourceMode() (errs []error) {
+	if len(def.Types) > 0 {
+		errs = append(errs, errors.New("types: not allowed with source; every interface in the source file is mocked"))
+	}
+	if _, err := os.Stat(def.sourceFile()); err != nil {
+		errs = append(errs, fmt.Errorf("source: %w", err))
+	}
+	return append(errs, def.validateAuxFiles()...)
+}
+
+// validateAuxFiles checks that each auxiliary file exists. Each error names the package whose file is missing.
+func (def *mockDefinition) validateAuxFiles() (errs []error) {
+	for _, pkg := range slices.Sorted(maps.Keys(def.AuxFiles)) {
+		if _, err := os.Stat(filepath.Join(def.Dir, def.AuxFiles[pkg])); err != nil {
+			errs = append(errs, fmt.Errorf("aux_files: %s: %w", pkg, err))
+		}
+	}
+	return errs
+}
+
+func (def *mockDefinition) validateFiles() (errs []error) {
+	if def.Destination != "" && (filepath.Base(def.Destination) != def.Destination ||
+		filepath.Ext(def.Destination) != ".go") {
+		errs = append(errs, fmt.Errorf("destination: %q must be a .go file name without a directory", def.Destination))
+	}
+	if file := def.copyrightFile(); file != "" {
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, fmt.Errorf("copyright_file: %w", err))
+		}
+	}
+	return errs
+}
+
+// validatePackageDir checks the options for a shared mock package.
+func (def *mockDefinition) validatePackageDir() (errs []error) {
+	if def.PackageDir == "" {
+		return nil
+	}
+	if !token.IsIdentifier(filepath.Base(def.PackageDir)) {
+		errs = append(errs, fmt.Errorf("package_dir: %q must end with a valid package name", def.PackageDir))
+	}
+	if def.External {
+		errs = append(errs, errors.New("external: not allowed with package_dir"))
+	}
+	return errs
+}
+
+// validate checks the definition for problems that mockgen would otherwise report less clearly, or not at all.
+func (def *mockDefinition) validate() []error {
+	return slices.Concat(def.validateTypes(), def.validateFiles(), def.validatePackageDir())
+}
+
+// unknownKeys reports any keys in the entry's mapping node that mockgenRec doesn't have a field for.
+func unknownKeys(node *yaml.Node) (errs []error) {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for key := range mappingEntries(node) {
+		if !slices.Contains(mockgenKeys, key.Value) {
+			errs = append(errs, fmt.Errorf("line %d: unknown key %q", key.Line, key.Value))
+		}
+	}
+	return errs
+}
+
+// decodeDefinition decodes and validates a single mockgen.yaml entry. Each error it returns is prefixed with the
+// entry's key.
+func decodeDefinition(dir, key string, node *yaml.Node) (mockDefinition, []error) {
+	def := mockDefinition{SourcePackage: key, Dir: dir}
+	errs := unknownKeys(node)
+	if err := node.Decode(&def.mockgenRec); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, def.validate()...)
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%s: %w", key, err)
+	}
+	return def, errs
+}
+
+// checkDestinations reports definitions that generate the same file, whether they name it with destination or get
+// the default name.
+func checkDestinations(defs []mockDefinition) (errs []error) {
+	seen := map[string]string{}
+	for _, def := range defs {
+		if def.Output == "" {
+			continue
+		}
+		if other, ok := seen[def.Output]; ok {
+			errs = append(errs, fmt.Errorf("%s: destination %s is also used by %s", def.SourcePackage,
+				def.Output, other))
+		}
+		seen[def.Output] = def.SourcePackage
+	}
+	return errs
+}
+
+// decodeDefinitions decodes every entry of the mockgen.yaml document and validates the whole file. It reports all the
+// problems it finds, not just the first.
+func decodeDefinitions(dir string, doc *yaml.Node) ([]mockDefinition, error) {
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping from package names to mock definitions", doc.Line)
+	}
+	var defs []mockDefinition
+	var errs []error
+	for key, value := range mappingEntries(doc) {
+		def, defErrs := decodeDefinition(dir, key.Value, value)
+		defs = append(defs, def)
+		errs = append(errs, defErrs...)
+	}
+	return defs, errors.Join(errs...)
+}
+
+// readConfig parses the given YAML file and returns its document node, or nil if the file is empty.
+func readConfig(configFile string) (*yaml.Node, error) {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err = yaml.Unmarshal(content, &root); err != nil || len(root.Content) == 0 {
+		return nil, err
+	}
+	return root.Content[0], nil
+}
+
+func loadRecords(dir string) ([]mockDefinition, error) {
+	configFile := filepath.Join(dir, mockgenConfig)
+	doc, err := readConfig(configFile)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	defs, err := decodeDefinitions(dir, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configFile, err)
+	}
+	// Sort the definitions so that failures get reported in a consistent order.
+	slices.SortFunc(defs, func(a, b mockDefinition) int {
+		return strings.Compare(a.SourcePackage, b.SourcePackage)
+	})
+	return defs, nil
+}
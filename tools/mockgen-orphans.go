@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rkennedy/magehelper"
+)
+
+// mockgenHeader is the comment that mockgen puts at the top of every file it generates. A copyright notice might come
+// before it.
+const mockgenHeader = "// Code generated by MockGen. DO NOT EDIT."
+
+const (
+	// manifestDirMode is the permission for creating the directory of manifest files.
+	manifestDirMode fs.FileMode = 0o755
+	// manifestFileMode is the permission for creating manifest files.
+	manifestFileMode fs.FileMode = 0o644
+)
+
+// defaultManifestDir is where [MockgenTask] keeps its manifests unless [MockgenTask.ManifestDir] says otherwise.
+var defaultManifestDir = filepath.Join("bin", "mockgen-manifests")
+
+// hasHeader reports whether the given generator header appears before the package clause in the given Go source.
+func hasHeader(r io.Reader, header string) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == header {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false, scanner.Err()
+}
+
+// generatedBy reports whether the given Go file has the given generator header.
+func generatedBy(fileName, header string) (bool, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	return hasHeader(f, header)
+}
+
+// manifestFile returns the name of the file that lists the mock files the task generated for its directory.
+func (fn *MockgenTask) manifestFile() string {
+	sum := sha256.Sum256([]byte(filepath.Clean(fn.dir)))
+	return filepath.Join(fn.manifestDir, hex.EncodeToString(sum[:])+".txt")
+}
+
+// readManifest returns the files listed in the task's manifest, or nothing if the task hasn't written one yet.
+func (fn *MockgenTask) readManifest() ([]string, error) {
+	content, err := os.ReadFile(fn.manifestFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.FieldsFunc(string(content), func(r rune) bool { return r == '\n' }), nil
+}
+
+// writeManifest records the files that the task's definitions generate. In dry-run mode, it does nothing, so the
+// previous manifest still lists the files that would have been removed.
+func (fn *MockgenTask) writeManifest(outputs []string) error {
+	if magehelper.DryRun() {
+		return nil
+	}
+	if err := os.MkdirAll(fn.manifestDir, manifestDirMode); err != nil {
+		return err
+	}
+	return os.WriteFile(fn.manifestFile(), []byte(strings.Join(outputs, "\n")+"\n"), manifestFileMode)
+}
+
+// isOrphan reports whether a file from the manifest is left over from a definition that's since been removed from
+// mockgen.yaml, or whose destination has changed. A file that no longer exists, or that no longer has mockgen's header
+// because something else has replaced it, isn't an orphan.
+func isOrphan(file string, outputs []string) (bool, error) {
+	if slices.Contains(outputs, file) {
+		return false, nil
+	}
+	generated, err := generatedBy(file, mockgenHeader)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return generated, err
+}
+
+// orphans returns the files in the manifest that are orphans.
+func orphans(manifest, outputs []string) (result []string, err error) {
+	for _, file := range manifest {
+		orphan, err := isOrphan(file, outputs)
+		if err != nil {
+			return nil, err
+		}
+		if orphan {
+			result = append(result, file)
+		}
+	}
+	return result, nil
+}
+
+// findOrphans returns the files in the task's manifest that are orphans.
+func (fn *MockgenTask) findOrphans(outputs []string) ([]string, error) {
+	manifest, err := fn.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	return orphans(manifest, outputs)
+}
+
+// removeOrphans deletes the files that the task generated previously but that don't correspond to any current mock
+// definition, and then it records the current outputs in the manifest.
+func (fn *MockgenTask) removeOrphans(ctx context.Context, outputs []string) error {
+	files, err := fn.findOrphans(outputs)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		magehelper.TaskLogger(ctx).Info("removing orphaned mock file", "file", file)
+		if err = magehelper.Rm(ctx, file); err != nil {
+			return err
+		}
+	}
+	return fn.writeManifest(outputs)
+}
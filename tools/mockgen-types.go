@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/rkennedy/magehelper"
+	"golang.org/x/tools/go/packages"
+)
+
+// declarationLoadMode asks for syntax and type information for the package and all its dependencies, so go/packages
+// type-checks everything from source instead of reading the compiler's export data. Positions from export data aren't
+// reliably absolute file names; builds with -trimpath, for instance, record them relative to the module root, and
+// standard-library positions are recorded relative to GOROOT.
+const declarationLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+	packages.NeedSyntax | packages.NeedTypes
+
+// declarationFinder collects the files that declare a set of interfaces and everything they embed.
+type declarationFinder struct {
+	fset  *token.FileSet
+	files mapset.Set[string]
+	seen  mapset.Set[*types.TypeName]
+}
+
+// add records the file declaring the given type, and then, if the type is an interface, the files declaring the
+// interfaces it embeds, which may belong to other local packages. Types from non-local packages, such as the standard
+// library, only change along with go.mod, so they're skipped, and so are predeclared types like error.
+func (finder *declarationFinder) add(obj *types.TypeName) {
+	if !localType(obj) || !finder.seen.Add(obj) {
+		return
+	}
+	if file := finder.fset.Position(obj.Pos()).Filename; file != "" {
+		finder.files.Add(file)
+	}
+	if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+		finder.addEmbedded(iface)
+	}
+}
+
+// localType reports whether the given type belongs to a package in [magehelper.LoadedPackages].
+func localType(obj *types.TypeName) bool {
+	if obj.Pkg() == nil {
+		return false
+	}
+	_, local := magehelper.LoadedPackages()[obj.Pkg().Path()]
+	return local
+}
+
+// addEmbedded adds the named interfaces that the given interface embeds.
+func (finder *declarationFinder) addEmbedded(iface *types.Interface) {
+	for embedded := range iface.EmbeddedTypes() {
+		if named, ok := types.Unalias(embedded).(*types.Named); ok {
+			finder.add(named.Origin().Obj())
+		}
+	}
+}
+
+// addTypes adds the named types from the given package.
+func (finder *declarationFinder) addTypes(pkg *types.Package, typeNames []string) error {
+	for _, typeName := range typeNames {
+		obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+		if !ok {
+			return fmt.Errorf("type %s not found in %s", typeName, pkg.Path())
+		}
+		finder.add(obj)
+	}
+	return nil
+}
+
+// packageErrors combines the errors that go/packages reported for the package and its dependencies.
+func packageErrors(pkgs []*packages.Package) error {
+	var errs []error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// declaringFiles type-checks the named package from source and returns the files that declare the given types, plus
+// the files that declare every interface those types embed, directly or indirectly, in any local package. Those are the
+// only files whose changes can affect the mocks that mockgen generates for the types.
+func declaringFiles(pkgPath string, typeNames []string) ([]string, error) {
+	fset := token.NewFileSet()
+	pkgs, err := packages.Load(&packages.Config{Mode: declarationLoadMode, Fset: fset}, pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = packageErrors(pkgs); err != nil {
+		return nil, err
+	}
+
+	finder := declarationFinder{
+		fset:  fset,
+		files: mapset.NewThreadUnsafeSet[string](),
+		seen:  mapset.NewThreadUnsafeSet[*types.TypeName](),
+	}
+	if err = finder.addTypes(pkgs[0].Types, typeNames); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(slices.Values(finder.files.ToSlice())), nil
+}
@@ -2,30 +2,38 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maps"
-	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
 	"sync"
 
 	"github.com/magefile/mage/mg"
 	"github.com/magefile/mage/sh"
-	"github.com/magefile/mage/target"
 	"github.com/rkennedy/magehelper"
 	"github.com/rkennedy/magehelper/iters"
-	"gopkg.in/yaml.v3"
 )
 
-const mockgenImport = "go.uber.org/mock/mockgen"
+const (
+	mockgenImport = "go.uber.org/mock/mockgen"
+	mockgenConfig = "mockgen.yaml"
+	// mockFileFormat is the format of the default name of a generated file.
+	mockFileFormat = "mock_%s_test.go"
+	// sharedMockFileFormat is the format of the default name of a file generated into a shared mock package.
+	sharedMockFileFormat = "mock_%s.go"
+)
 
 // MockgenTask is a Mage task that generates mock types for code in a particular directory.
 type MockgenTask struct {
 	mockgenBin string
 	modDir     string
 
-	dir string
+	dir         string
+	concurrency int
+	manifestDir string
 }
 
 var _ mg.Fn = &MockgenTask{}
@@ -35,8 +43,18 @@ var _ mg.Fn = &MockgenTask{}
 // versions of. The YAML should have the structure of the following type:
 //
 //	var yamlStructure map[string]struct {
-//	    external bool
-//	    types    []string
+//	    external              bool
+//	    types                 []string
+//	    destination           string
+//	    mock_names            map[string]string
+//	    self_package          string
+//	    build_constraint      string
+//	    typed                 bool
+//	    copyright_file        string
+//	    write_package_comment bool
+//	    source                string
+//	    aux_files             map[string]string
+//	    package_dir           string
 //	}
 //
 // That is, the YAML should be an object whose keys are the names of the packages whose types need to be mocked. The
@@ -44,14 +62,45 @@ var _ mg.Fn = &MockgenTask{}
 // whether the generated code should be in the package of the current directory (false) or in the "_test" package
 // (true).
 //
+// The remaining fields are optional. Destination names the generated file, which otherwise is mock_<package>_test.go;
+// it must be a file name in dir, not a path. Mock_names maps type names to the names of their mocks. Copyright_file is
+// relative to dir, and it is a dependency of the generated file. The other fields correspond to the mockgen options of
+// the same names.
+//
+// Setting source selects mockgen's source mode, which mocks every interface declared in the given file, relative to
+// dir. It's useful for mocking unexported interfaces or interfaces declared in test files. In source mode, the key is
+// just a label for the entry, types must be empty, and the generated file defaults to mock_<file>_test.go, named after
+// the source file without any _test suffix. Aux_files maps package names to files, relative to dir, that declare
+// interfaces embedded by those in the source file. Like the source file, the auxiliary files are dependencies of the
+// generated file, so changing any of them causes the mocks to be generated again.
+//
+// The whole file is validated before any mocks are generated. Unknown keys, invalid values, and entries that would
+// generate the same file are all reported together, each identified by the package it belongs to.
+//
+// Packages are mocked concurrently, subject to [MockgenTask.Concurrency]. If mocking any package fails, the task still
+// finishes the others, and then it fails with an error listing every package that failed.
+//
+// Package_dir names a directory, relative to dir, for a shared mock package that any other package may import, such
+// as internal/mocks/mockio. The generated file goes there instead of in dir, it's an ordinary Go file named
+// mock_<package>.go by default, and its package name is the last element of the directory. After generating shared
+// mocks, the task calls [magehelper.ReloadPackages] so the new package is available for dependency tracking.
+//
+// After mocking every package successfully, the task records the generated files in a manifest, kept in the directory
+// set by [MockgenTask.ManifestDir]. Files listed in the previous manifest that aren't the output of a current
+// definition, including files in shared mock packages, are deleted if they still have mockgen's "Code generated"
+// header. Those files are left over from packages or types that have been removed from mockgen.yaml, and they often no
+// longer compile. Files that the task didn't generate, such as those from go:generate directives, are left alone. In
+// dry-run mode, the task only reports the files it would delete.
+//
 // The task will run mockgen with the given path and binary name, and it will be installed according to the version
 // requested in the active go.mod, specified by [Mockgen.ModDir].
 //
 // When mockgen.yaml calls for mocking types from another package in the same module, that's referred to as a "local"
-// package, and all that package's source files will be included as dependencies for the generated mock source file,
-// along with mockgen.yaml itself. If the mocked types come from a non-local package (i.e., a Go built-in package or a
-// third-party package), then only mockgen.yaml is a dependency. When dependencies are newer than the generated mock
-// source file, then the source will gen regenerated.
+// package. The files that declare the mocked types, and the files that declare any interfaces they embed, even from
+// other local packages, will be included as dependencies for the generated mock source file, along with mockgen.yaml
+// itself. If the mocked types come from a non-local package (i.e., a Go built-in package or a third-party package),
+// then only mockgen.yaml is a dependency. When dependencies are newer than the generated mock source file, then the
+// source will be regenerated.
 //
 // To mock the [io.ReaderAt], [io.WriterAt], and [github.com/logrusorgru/aurora/v3.Aurora] interfaces, specify a
 // mockgen.yaml file like this:
@@ -67,8 +116,9 @@ var _ mg.Fn = &MockgenTask{}
 //	  - Aurora
 func Mockgen(mockgenBin, dir string) *MockgenTask {
 	return &MockgenTask{
-		mockgenBin: mockgenBin,
-		dir:        dir,
+		mockgenBin:  mockgenBin,
+		dir:         dir,
+		manifestDir: defaultManifestDir,
 	}
 }
 
@@ -81,6 +131,13 @@ func (fn *MockgenTask) ModDir(dir string) *MockgenTask {
 	return fn
 }
 
+// ManifestDir sets the directory where the task keeps the manifests that list the files it generated for each
+// directory. The default is bin/mockgen-manifests. ManifestDir returns the MockgenTask.
+func (fn *MockgenTask) ManifestDir(dir string) *MockgenTask {
+	fn.manifestDir = dir
+	return fn
+}
+
 // Name implements [mg.Fn].
 func (fn MockgenTask) Name() string {
 	return fmt.Sprintf("Mockgen directory %s", fn.dir)
@@ -91,133 +148,175 @@ func (fn MockgenTask) ID() string {
 	return fmt.Sprintf("magehelper mockgen %s", fn.dir)
 }
 
-// Each directory that _uses_ mocks will have a mockgen.yaml file listing all the packages and types that it needs mocks
-// from.
-type mockgenRec struct {
-	External bool     `yaml:"external"`
-	Types    []string `yaml:"types"`
-}
-
-type mockDefinition struct {
-	SourcePackage string
-	External      bool
-	Types         []string
+// fanOutPackages mocks each package in its own goroutine, running no more than the configured number at once. It
+// returns the names of the files for all the definitions, whether they needed to be generated or not, along with the
+// errors from all the packages that failed, including those that never started because ctx was canceled.
+func (fn *MockgenTask) fanOutPackages(ctx context.Context, defs []mockDefinition) ([]string, error) {
+	outputs := make([]string, len(defs))
+	errs := make([]error, len(defs))
+	sem := make(chan struct{}, fn.concurrencyLimit())
+	var wg sync.WaitGroup
+	for i, def := range defs {
+		if errs[i] = fn.acquire(ctx, sem, def); errs[i] != nil {
+			continue
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			outputs[i], errs[i] = fn.mockPackage(ctx, def)
+		})
+	}
+	wg.Wait()
+	return outputs, errors.Join(errs...)
 }
 
-func (def *mockDefinition) OutputPackageName(basePackage string) string {
-	if def.External {
-		return basePackage + "_test"
+// acquire waits for a turn to run mockgen, or for ctx to be canceled.
+func (fn *MockgenTask) acquire(ctx context.Context, sem chan<- struct{}, def mockDefinition) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fn.packageError(def, ctx.Err())
 	}
-	return basePackage
 }
 
-func loadRecords(dir string) ([]mockDefinition, error) {
-	in, err := os.Open(filepath.Join(dir, "mockgen.yaml"))
-	if err != nil {
-		return nil, err
+func (fn *MockgenTask) packageError(def mockDefinition, err error) error {
+	if err == nil {
+		return nil
 	}
-	defer in.Close()
+	return fmt.Errorf("mock %s in %s: %w", def.SourcePackage, fn.dir, err)
+}
 
-	decoder := yaml.NewDecoder(in)
-	recs := map[string]mockgenRec{}
-	err = decoder.Decode(&recs)
-	if err != nil {
-		return nil, err
+func (fn *MockgenTask) concurrencyLimit() int {
+	if fn.concurrency > 0 {
+		return fn.concurrency
 	}
-	return slices.Collect(iters.MapTransform(maps.All(recs), func(pkgName string, rec mockgenRec) mockDefinition {
-		return mockDefinition{
-			SourcePackage: pkgName,
-			External:      rec.External,
-			Types:         rec.Types,
-		}
-	})), err
+	return runtime.GOMAXPROCS(0)
 }
 
-func (fn *MockgenTask) fanOutPackages(ctx context.Context, defs []mockDefinition) {
-	var wg sync.WaitGroup
-	defer wg.Wait()
-
-	for _, def := range defs {
-		wg.Add(1)
-		go fn.mockPackage(ctx, &wg, def)
-	}
+// Concurrency sets the maximum number of mockgen processes the task runs at once. The default is
+// [runtime.GOMAXPROCS]. Concurrency returns the MockgenTask.
+func (fn *MockgenTask) Concurrency(n int) *MockgenTask {
+	fn.concurrency = n
+	return fn
 }
 
 // Run implements [mg.Fn].
 func (fn *MockgenTask) Run(ctx context.Context) error {
-	dir, err := filepath.Abs(fn.dir)
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *MockgenTask) execute(ctx context.Context) error {
+	recs, err := loadRecords(fn.dir)
 	if err != nil {
 		return err
 	}
-	fn.dir = dir
 
-	recs, err := loadRecords(fn.dir)
+	magehelper.Deps(ctx,
+		magehelper.LoadDependencies,
+		magehelper.Install(fn.mockgenBin, mockgenImport).ModDir(fn.modDir),
+	)
+	return fn.generate(ctx, recs)
+}
+
+// resolveOutputs determines the name of the file that each definition generates, and then it reports definitions
+// that would generate the same file.
+func (fn *MockgenTask) resolveOutputs(defs []mockDefinition) error {
+	errs := make([]error, len(defs))
+	for i := range defs {
+		defs[i].Output, errs[i] = outputName(defs[i])
+		errs[i] = fn.packageError(defs[i], errs[i])
+	}
+	return errors.Join(append(errs, checkDestinations(defs)...)...)
+}
+
+// generate determines every definition's output file, mocks every definition, and then removes the mock files that no
+// definition accounts for.
+func (fn *MockgenTask) generate(ctx context.Context, defs []mockDefinition) error {
+	if err := fn.resolveOutputs(defs); err != nil {
+		return err
+	}
+	outputs, err := fn.fanOutPackages(ctx, defs)
 	if err != nil {
+		// Without every definition's output, there's no telling which generated files are orphans.
 		return err
 	}
+	if err = fn.removeOrphans(ctx, outputs); err != nil {
+		return err
+	}
+	return reloadSharedPackages(ctx, defs)
+}
 
-	fn.fanOutPackages(ctx, recs)
-	return nil
+// reloadSharedPackages reloads the packages if any of the definitions generates a shared mock package, so that tasks
+// that depend on the consumers of those mocks see the new package and its files.
+func reloadSharedPackages(ctx context.Context, defs []mockDefinition) error {
+	if !slices.ContainsFunc(defs, func(def mockDefinition) bool { return def.PackageDir != "" }) {
+		return nil
+	}
+	return magehelper.ReloadPackages(ctx)
 }
 
-func outputFileIfNeedsBuild(
-	ctx context.Context,
-	directory, sourcePackage string,
-) (needsBuild bool, outputFile string, err error) {
-	outFileName, files, err := outputAndInputs(ctx, directory, sourcePackage)
+// outputFileIfNeedsBuild returns the name of the definition's output file and whether it needs to be generated.
+func outputFileIfNeedsBuild(ctx context.Context, def mockDefinition) (needsBuild bool, outputFile string, err error) {
+	files, err := inputs(def)
 	if err != nil {
 		return false, "", err
 	}
-	needsBuild, err = target.Path(outFileName, files...)
+	needsBuild, err = magehelper.Stale(ctx, def.Output, files...)
 	if err != nil {
 		return false, "", err
 	}
 	if !needsBuild {
-		magehelper.LogV("File %s is up to date.\n", outFileName)
-		return false, "", nil
+		magehelper.TaskLogger(ctx).Debug("file is up to date", "file", def.Output)
 	}
-	return true, outFileName, nil
+	return needsBuild, def.Output, nil
 }
 
-func (fn *MockgenTask) mockPackage(ctx context.Context, wg *sync.WaitGroup, def mockDefinition) error {
-	defer wg.Done()
-
-	needsBuild, outFileName, err := outputFileIfNeedsBuild(ctx, fn.dir, def.SourcePackage)
-	if err != nil {
-		return err
+// mockPackage generates the definition's mocks, if necessary, and returns the name of the output file.
+func (fn *MockgenTask) mockPackage(ctx context.Context, def mockDefinition) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fn.packageError(def, err)
 	}
-	if !needsBuild {
-		return nil
+	needsBuild, outFileName, err := outputFileIfNeedsBuild(ctx, def)
+	if err != nil || !needsBuild {
+		return outFileName, fn.packageError(def, err)
 	}
 
-	return fn.mockSinglePackage(ctx, outFileName, def)
+	return outFileName, fn.packageError(def, fn.mockSinglePackage(ctx, outFileName, def))
 }
 
-// outputAndInputs determines the full name and path of the file to be generated, as well as the files that contribute
-// to its generation. For a non-local package, that's just mockgen.yaml, but for a package that's part of the same
-// project, the inputs include the source files for that project as well.
-func outputAndInputs(ctx context.Context, dir, packageName string) (targetGoName string, files []string, err error) {
-	mg.CtxDeps(ctx, magehelper.LoadDependencies)
-
-	files = []string{filepath.Join(dir, "mockgen.yaml")}
-	pkg, ok := magehelper.Packages[packageName]
-	if ok {
-		// It's a local package.
+// outputName determines the full name and path of the file to be generated. Unless the definition names the file, its
+// name is based on the source file in source mode, and on the name of the mocked package otherwise.
+func outputName(def mockDefinition) (string, error) {
+	if def.Source != "" {
+		return def.outputFile(sourceModeName(def.Source)), nil
+	}
+	if pkg, ok := magehelper.LoadedPackages()[def.SourcePackage]; ok {
+		return def.outputFile(pkg.Name), nil
+	}
+	// It's not a local package.
+	pkgName, err := sh.Output(mg.GoCmd(), "list", "-f", "{{.Name}}", def.SourcePackage)
+	return def.outputFile(pkgName), err
+}
 
-		// We can add dependencies on the source files of that package, although we don't know precisely which
-		// source files truly define the interfaces we're mocking.
-		files = slices.AppendSeq(files, iters.SliceTransform(slices.Values(pkg.GoFiles), func(file string) string {
-			return filepath.Join(pkg.Dir, file)
-		}))
+// sourceModeName returns the name on which to base the default name of the file generated from the given source file.
+// It's the source file's name, less any _test suffix, so mocks for foo_test.go go in mock_foo_test.go.
+func sourceModeName(source string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(source), ".go"), "_test")
+}
 
-		targetGoName = fmt.Sprintf("mock_%s_test.go", pkg.Name)
-	} else {
-		// It's not a local package.
-		var pkgName string
-		pkgName, err = sh.Output(mg.GoCmd(), "list", "-f", "{{.Name}}", packageName)
-		targetGoName = fmt.Sprintf("mock_%s_test.go", pkgName)
+// inputs determines the files that contribute to the generation of the definition's output. For a non-local package,
+// that's just mockgen.yaml and any copyright file, but for a package that's part of the same project, the inputs
+// include the files that declare the mocked types and the interfaces they embed. In source mode, the inputs include
+// the source file and the auxiliary files; mockgen reads nothing else.
+func inputs(def mockDefinition) ([]string, error) {
+	files := append([]string{filepath.Join(def.Dir, mockgenConfig)}, def.inputs()...)
+	if _, ok := magehelper.LoadedPackages()[def.SourcePackage]; !ok || def.Source != "" {
+		return files, nil
 	}
-	return filepath.Join(dir, targetGoName), files, err
+	// It's a local package, so the files that declare the mocked interfaces, and the interfaces they embed, are
+	// dependencies.
+	packageFiles, err := declaringFiles(def.SourcePackage, def.Types)
+	return append(files, packageFiles...), err
 }
 
 func (fn *MockgenTask) mockSinglePackage(
@@ -225,22 +324,30 @@ func (fn *MockgenTask) mockSinglePackage(
 	outFileName string,
 	def mockDefinition,
 ) error {
-	pkgForDir, ok := iters.SliceSelectFirst(maps.Values(magehelper.Packages), func(pkg magehelper.Package) bool {
-		return pkg.Dir == fn.dir
+	basePackage, err := fn.basePackage(def)
+	if err != nil {
+		return err
+	}
+	return magehelper.RunV(ctx, fn.mockgenBin, def.args(outFileName, basePackage)...)
+}
+
+// basePackage returns the name of the package in the task's directory, which the generated code belongs to (or to
+// whose _test package it belongs). Mocks in a shared package don't belong to that directory, so it doesn't need to
+// have a package.
+func (fn *MockgenTask) basePackage(def mockDefinition) (string, error) {
+	if def.PackageDir != "" {
+		return "", nil
+	}
+	dir, err := filepath.Abs(fn.dir)
+	if err != nil {
+		return "", err
+	}
+	pkgs := maps.Values(magehelper.LoadedPackages())
+	pkgForDir, ok := iters.SliceSelectFirst(pkgs, func(pkg magehelper.Package) bool {
+		return pkg.Dir == dir
 	})
 	if !ok {
-		return fmt.Errorf("No package found for directory %s", fn.dir)
+		return "", fmt.Errorf("No package found for directory %s", fn.dir)
 	}
-
-	mg.CtxDeps(ctx,
-		magehelper.Install(fn.mockgenBin, mockgenImport).ModDir(fn.modDir),
-	)
-	return sh.RunV(
-		fn.mockgenBin,
-		"-destination", outFileName,
-		"-package", def.OutputPackageName(pkgForDir.Name),
-		// TODO? "-self_package", "???",
-		def.SourcePackage,
-		strings.Join(def.Types, ","),
-	)
+	return pkgForDir.Name, nil
 }
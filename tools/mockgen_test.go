@@ -1,6 +1,7 @@
 package tools_test
 
 import (
+	"context"
 	"go/ast"
 	"go/parser"
 	"go/token"
@@ -8,11 +9,14 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
-	"github.com/magefile/mage/mage"
+	"github.com/rkennedy/magehelper/iters"
+	"github.com/rkennedy/magehelper/tools"
 )
 
 var thisDir = filepath.Join("examples", "mockgen")
@@ -38,15 +42,21 @@ func typeDecls(node ast.Node) iter.Seq[*ast.TypeSpec] {
 }
 
 var _ = Describe("Mockgen", Ordered, func() {
+	manifestDir := filepath.Join(thisDir, "bin", "mockgen-manifests")
+
 	BeforeAll(func() {
+		removeAfterAll(
+			filepath.Join(thisDir, "mock_typed_test.go"),
+			filepath.Join(thisDir, "internal"),
+			filepath.Join(thisDir, "mock_io_test.go"),
+			filepath.Join(thisDir, "subdir", "mock_aurora_test.go"),
+			filepath.Join(thisDir, "typed", "fake_io_test.go"),
+			filepath.Join(thisDir, "typed", "mock_store_test.go"),
+			manifestDir,
+		)
+		Expect(os.RemoveAll(manifestDir)).To(Succeed())
 		By("building the example project")
-		inv := mage.Invocation{
-			Dir:    thisDir,
-			Stdout: GinkgoWriter,
-			Stderr: GinkgoWriter,
-			Args:   []string{"generate"},
-		}
-		Expect(mage.Invoke(inv)).To(Equal(0), "Build should exit successfully.")
+		invokeExample(thisDir, "generate")
 	})
 
 	It("produces a valid root source file", func() {
@@ -87,9 +97,190 @@ var _ = Describe("Mockgen", Ordered, func() {
 		))
 	})
 
-	AfterAll(func() {
-		// delete generated files
-		os.Remove(filepath.Join(thisDir, "mock_io_test.go"))
-		os.Remove(filepath.Join(thisDir, "subdir", "mock_aurora_test.go"))
+	It("applies mockgen.yaml options", func() {
+		tree, err := parser.ParseFile(token.NewFileSet(), filepath.Join(thisDir, "typed", "fake_io_test.go"), nil,
+			parser.SkipObjectResolution)
+		Expect(tree, err).NotTo(BeNil())
+
+		Expect(tree).To(HaveField("Name.Name", Equal("typed")))
+
+		names := slices.Collect(iters.SliceTransform(typeDecls(tree), func(spec *ast.TypeSpec) string {
+			return spec.Name.Name
+		}))
+		Expect(names).To(ContainElements("FakeReader", "FakeReaderReadCall"))
+	})
+
+	It("mocks source files", func() {
+		tree, err := parser.ParseFile(token.NewFileSet(), filepath.Join(thisDir, "typed", "mock_store_test.go"), nil,
+			parser.SkipObjectResolution)
+		Expect(tree, err).NotTo(BeNil())
+
+		Expect(tree).To(HaveField("Name.Name", Equal("typed")))
+
+		names := slices.Collect(iters.SliceTransform(typeDecls(tree), func(spec *ast.TypeSpec) string {
+			return spec.Name.Name
+		}))
+		Expect(names).To(ContainElement("Mockstore"))
+	})
+
+	It("depends only on the files declaring mocked types", func() {
+		declaring := filepath.Join(thisDir, "typed", "cache.go")
+		embedded := filepath.Join(thisDir, "typed", "flush", "flush.go")
+		now := time.Now()
+		for file, t := range map[string]time.Time{
+			filepath.Join(thisDir, "mockgen.yaml"): now.Add(-2 * time.Hour),
+			declaring:                              now.Add(-2 * time.Hour),
+			embedded:                               now.Add(-2 * time.Hour),
+			filepath.Join(thisDir, "mock_typed_test.go"): now.Add(-time.Hour),
+			filepath.Join(thisDir, "typed", "typed.go"):  now,
+		} {
+			Expect(os.Chtimes(file, t, t)).To(Succeed())
+		}
+		wouldGenerate := MatchRegexp(`would run.*mock_typed_test\.go`)
+
+		By("changing an unrelated file in the mocked package")
+		Expect(dryRunExample(thisDir, "generate")).NotTo(wouldGenerate)
+
+		By("changing the file in another package that declares an embedded interface")
+		Expect(os.Chtimes(embedded, now, now)).To(Succeed())
+		Expect(dryRunExample(thisDir, "generate")).To(wouldGenerate)
+
+		By("changing the file that declares the mocked type")
+		Expect(os.Chtimes(embedded, now.Add(-2*time.Hour), now.Add(-2*time.Hour))).To(Succeed())
+		Expect(os.Chtimes(declaring, now, now)).To(Succeed())
+		Expect(dryRunExample(thisDir, "generate")).To(wouldGenerate)
+	})
+
+	It("generates shared mock packages", func() {
+		tree, err := parser.ParseFile(token.NewFileSet(),
+			filepath.Join(thisDir, "internal", "mocks", "mockfs", "mock_fs.go"), nil, parser.SkipObjectResolution)
+		Expect(tree, err).NotTo(BeNil())
+
+		Expect(tree).To(HaveField("Name.Name", Equal("mockfs")))
+
+		names := slices.Collect(iters.SliceTransform(typeDecls(tree), func(spec *ast.TypeSpec) string {
+			return spec.Name.Name
+		}))
+		Expect(names).To(ContainElement("MockFS"))
+	})
+
+	It("removes mock files left over from removed definitions", func() {
+		config := filepath.Join(thisDir, "typed", "mockgen.yaml")
+		original, err := os.ReadFile(config)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.WriteFile, config, original, os.FileMode(0o644))
+
+		By("generating mocks for an additional definition")
+		Expect(os.WriteFile(config, append(slices.Clone(original),
+			"fmt:\n  types: [Stringer]\n  destination: mock_removed_test.go\n"...), 0o644)).To(Succeed())
+		invokeExample(thisDir, "generate")
+		removed := filepath.Join(thisDir, "typed", "mock_removed_test.go")
+		Expect(removed).To(BeAnExistingFile())
+
+		By("removing the definition")
+		handWritten := filepath.Join(thisDir, "typed", "mock_other_test.go")
+		Expect(os.WriteFile(handWritten, []byte("// Code generated by MockGen. DO NOT EDIT.\n\npackage typed\n"),
+			0o644)).To(Succeed())
+		DeferCleanup(os.Remove, handWritten)
+		Expect(os.WriteFile(config, original, 0o644)).To(Succeed())
+		invokeExample(thisDir, "generate")
+
+		Expect(removed).NotTo(BeAnExistingFile())
+		Expect(handWritten).To(BeAnExistingFile())
+		Expect(filepath.Join(thisDir, "typed", "fake_io_test.go")).To(BeAnExistingFile())
+		Expect(filepath.Join(thisDir, "typed", "typed.go")).To(BeAnExistingFile())
+	})
+
+	It("records generated files relative to the module root", func() {
+		manifests, err := filepath.Glob(filepath.Join(manifestDir, "*.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		var files []string
+		for _, manifest := range manifests {
+			content, err := os.ReadFile(manifest)
+			Expect(err).NotTo(HaveOccurred())
+			files = append(files, strings.Fields(string(content))...)
+		}
+		Expect(files).To(ContainElements("mock_io_test.go", filepath.Join("subdir", "mock_aurora_test.go")))
+		Expect(files).NotTo(ContainElement(WithTransform(filepath.IsAbs, BeTrue())))
+	})
+})
+
+var _ = Describe("Mockgen failures", func() {
+	It("reports every configuration error", func(ctx context.Context) {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "mockgen.yaml"), []byte(`
+io:
+  typs: [Reader]
+  destination: sub/mock.go
+  mock_names:
+    Writer: FakeWriter
+`), 0o644)).To(Succeed())
+
+		err := tools.Mockgen(filepath.Join(dir, "mockgen"), dir).ModDir(thisDir).Run(ctx)
+		Expect(err).To(SatisfyAll(
+			MatchError(ContainSubstring(`io: line 3: unknown key "typs"`)),
+			MatchError(ContainSubstring("io: types:")),
+			MatchError(ContainSubstring("io: destination:")),
+			MatchError(ContainSubstring("io: mock_names: Writer")),
+		))
+	})
+
+	It("reports missing auxiliary files by package", func(ctx context.Context) {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "store_test.go"), []byte("package store\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "other.go"), []byte("package store\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "mockgen.yaml"), []byte(`
+local:
+  source: store_test.go
+  aux_files:
+    example.com/present: other.go
+    example.com/absent: missing.go
+`), 0o644)).To(Succeed())
+
+		err := tools.Mockgen(filepath.Join(dir, "mockgen"), dir).ModDir(thisDir).Run(ctx)
+		Expect(err).To(SatisfyAll(
+			MatchError(ContainSubstring("local: aux_files: example.com/absent: ")),
+			MatchError(ContainSubstring("missing.go")),
+			Not(MatchError(ContainSubstring("example.com/present"))),
+		))
+	})
+
+	It("reports definitions that generate the same file", func(ctx context.Context) {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "store_test.go"), []byte("package store\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "mockgen.yaml"), []byte(`
+io:
+  types: [Reader]
+  destination: mock_fs_test.go
+io/fs:
+  types: [FS]
+local:
+  source: store_test.go
+os:
+  types: [Signal]
+  destination: mock_store_test.go
+`), 0o644)).To(Succeed())
+
+		err := tools.Mockgen(filepath.Join(dir, "mockgen"), dir).ModDir(thisDir).Run(ctx)
+		Expect(err).To(SatisfyAll(
+			MatchError(ContainSubstring("io/fs: destination "+filepath.Join(dir, "mock_fs_test.go"))),
+			MatchError(ContainSubstring("os: destination "+filepath.Join(dir, "mock_store_test.go"))),
+		))
+	})
+
+	It("reports every package that fails", func(ctx context.Context) {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "mockgen.yaml"), []byte(`
+example.invalid/first:
+  types: [First]
+example.invalid/second:
+  types: [Second]
+`), 0o644)).To(Succeed())
+
+		err := tools.Mockgen(filepath.Join(dir, "mockgen"), dir).ModDir(thisDir).Run(ctx)
+		Expect(err).To(SatisfyAll(
+			MatchError(ContainSubstring("example.invalid/first")),
+			MatchError(ContainSubstring("example.invalid/second")),
+		))
 	})
 })
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// oapiOverlay is the overlay section of an oapi-codegen configuration file.
+type oapiOverlay struct {
+	Path string `yaml:"path"`
+}
+
+// oapiOutputOptions holds the parts of an oapi-codegen configuration file's output-options section that
+// [OapiCodegen] needs.
+type oapiOutputOptions struct {
+	Overlay oapiOverlay `yaml:"overlay"`
+}
+
+// oapiConfig holds the parts of an oapi-codegen configuration file that [OapiCodegen] needs.
+type oapiConfig struct {
+	Output        string            `yaml:"output"`
+	OutputOptions oapiOutputOptions `yaml:"output-options"`
+}
+
+// readOapiConfig reads the given oapi-codegen configuration file, which must name an output file.
+func readOapiConfig(file string) (config oapiConfig, err error) {
+	if err = readYAML(file, &config); err != nil {
+		return config, err
+	}
+	if config.Output == "" {
+		return config, fmt.Errorf("%s: no output file configured", file)
+	}
+	return config, nil
+}
+
+// inputs returns the files named in the configuration that the generated code depends on. Paths are relative to the
+// given directory, which holds the configuration file.
+func (c *oapiConfig) inputs(dir string) []string {
+	if c.OutputOptions.Overlay.Path == "" {
+		return nil
+	}
+	return []string{filepath.Join(dir, c.OutputOptions.Overlay.Path)}
+}
+
+// mappingRef returns the value of the given mapping node's $ref key, or the empty string if the node isn't a mapping
+// or has no reference.
+func mappingRef(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for key, value := range mappingEntries(node) {
+		if key.Value == "$ref" && value.Kind == yaml.ScalarNode {
+			return value.Value
+		}
+	}
+	return ""
+}
+
+// specRefs returns all the $ref values in the given document tree.
+func specRefs(node *yaml.Node) (refs []string) {
+	if ref := mappingRef(node); ref != "" {
+		refs = append(refs, ref)
+	}
+	for _, child := range node.Content {
+		refs = append(refs, specRefs(child)...)
+	}
+	return refs
+}
+
+// refFile returns the file that a $ref in the given spec file refers to. Local references, which start with #, and
+// remote references, which are URLs, resolve to the empty string.
+func refFile(spec, ref string) string {
+	path, _, _ := strings.Cut(ref, "#")
+	if path == "" || strings.Contains(path, "://") {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(spec), filepath.FromSlash(path))
+}
+
+// externalRefs returns the files that the given spec file refers to. Specs may be either YAML or JSON.
+func externalRefs(spec string) (files []string, err error) {
+	content, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	for _, ref := range specRefs(&root) {
+		if file := refFile(spec, ref); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// refClosure returns the given spec file along with all the files it refers to, directly or indirectly.
+func refClosure(spec string) ([]string, error) {
+	seen := mapset.NewThreadUnsafeSet(filepath.Clean(spec))
+	for queue := []string{filepath.Clean(spec)}; len(queue) > 0; queue = queue[1:] {
+		refs, err := externalRefs(queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, slices.DeleteFunc(refs, func(file string) bool { return !seen.Add(file) })...)
+	}
+	return slices.Sorted(slices.Values(seen.ToSlice())), nil
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+const oapiCodegenImport = "github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen"
+
+// OapiCodegenTask is a Mage task that generates Go code from an OpenAPI specification with oapi-codegen.
+type OapiCodegenTask struct {
+	bin     string
+	spec    string
+	configs []string
+	modDir  string
+}
+
+var _ mg.Fn = &OapiCodegenTask{}
+
+// OapiCodegen returns a [mg.Fn] that installs oapi-codegen at the version in go.mod and runs it on the given OpenAPI
+// specification once for each of the given configuration files. Each run happens in the directory holding its
+// configuration file, so paths in the file, such as output, are relative to that directory, just as when running
+// oapi-codegen from a go:generate directive next to its configuration.
+//
+// Each configuration file must name its output file. The output is out of date when it's older than the
+// configuration file, the overlay that the configuration file names, the oapi-codegen binary, the specification, or
+// any file that the specification refers to with $ref, directly or indirectly. After generating code, the task calls
+// [magehelper.ReloadPackages].
+func OapiCodegen(bin, spec string, configs ...string) *OapiCodegenTask {
+	return &OapiCodegenTask{
+		bin:     bin,
+		spec:    spec,
+		configs: configs,
+	}
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of oapi-codegen
+// this project uses. ModDir returns the OapiCodegenTask.
+func (fn *OapiCodegenTask) ModDir(dir string) *OapiCodegenTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (fn *OapiCodegenTask) Name() string {
+	return fmt.Sprintf("Oapi-codegen %s", fn.spec)
+}
+
+// ID implements [mg.Fn].
+func (fn *OapiCodegenTask) ID() string {
+	return fmt.Sprintf("magehelper oapi-codegen %s %s", fn.spec, strings.Join(fn.configs, " "))
+}
+
+// Run implements [mg.Fn].
+func (fn *OapiCodegenTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *OapiCodegenTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.Install(fn.bin, oapiCodegenImport).ModDir(fn.modDir))
+	specFiles, err := refClosure(fn.spec)
+	if err != nil {
+		return err
+	}
+	generated, err := fn.generateAll(ctx, specFiles)
+	if err != nil || !generated {
+		return err
+	}
+	return magehelper.ReloadPackages(ctx)
+}
+
+// generateAll runs oapi-codegen for each configuration file whose output is out of date. It reports whether it ran
+// anything.
+func (fn *OapiCodegenTask) generateAll(ctx context.Context, specFiles []string) (generated bool, err error) {
+	for _, config := range fn.configs {
+		ran, err := fn.generateIfStale(ctx, config, specFiles)
+		if err != nil {
+			return false, err
+		}
+		generated = generated || ran
+	}
+	return generated, nil
+}
+
+// generateIfStale runs oapi-codegen with the given configuration file if its output is out of date. It reports whether
+// it ran.
+func (fn *OapiCodegenTask) generateIfStale(ctx context.Context, config string, specFiles []string) (bool, error) {
+	options, err := readOapiConfig(config)
+	if err != nil {
+		return false, err
+	}
+	dir := filepath.Dir(config)
+	inputs := slices.Concat([]string{config, fn.bin}, specFiles, options.inputs(dir))
+	stale, err := magehelper.Stale(ctx, filepath.Join(dir, options.Output), inputs...)
+	if err != nil || !stale {
+		return false, err
+	}
+	return true, fn.generate(ctx, config)
+}
+
+// generate runs oapi-codegen with the given configuration file.
+func (fn *OapiCodegenTask) generate(ctx context.Context, config string) error {
+	bin, err := filepath.Abs(fn.bin)
+	if err != nil {
+		return err
+	}
+	spec, err := filepath.Abs(fn.spec)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(bin, "-config", filepath.Base(config), spec)
+	cmd.Dir = filepath.Dir(config)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return magehelper.RunCmd(ctx, cmd)
+}
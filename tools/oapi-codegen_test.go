@@ -0,0 +1,44 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var oapiCodegenDir = filepath.Join("examples", "oapi-codegen")
+
+var _ = Describe("OapiCodegen", Ordered, func() {
+	apiDir := filepath.Join(oapiCodegenDir, "api")
+	commonSpec := filepath.Join(apiDir, "common", "openapi.yaml")
+
+	BeforeAll(func() {
+		removeAfterAll(
+			filepath.Join(apiDir, "types.gen.go"),
+			filepath.Join(apiDir, "client.gen.go"),
+			filepath.Join(apiDir, "common", "types.gen.go"),
+		)
+		By("generating code")
+		invokeExample(oapiCodegenDir, "generate")
+	})
+
+	It("generates code for every configuration", func() {
+		Expect(os.ReadFile(filepath.Join(apiDir, "types.gen.go"))).To(ContainSubstring("type Pet struct"))
+		Expect(os.ReadFile(filepath.Join(apiDir, "client.gen.go"))).To(ContainSubstring("func NewClient("))
+		Expect(os.ReadFile(filepath.Join(apiDir, "common", "types.gen.go"))).To(ContainSubstring("type Person struct"))
+	})
+
+	It("doesn't regenerate up-to-date code", func() {
+		Expect(dryRunExample(oapiCodegenDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("regenerates code when a referenced spec changes", func() {
+		touchLater(commonSpec)
+		Expect(dryRunExample(oapiCodegenDir, "generate")).To(SatisfyAll(
+			MatchRegexp(`would run.*-config types.cfg.yaml \S*/api/openapi.yaml`),
+			MatchRegexp(`would run.*-config client.cfg.yaml \S*/api/openapi.yaml`),
+		))
+	})
+})
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/magefile/mage/mg"
-	"github.com/magefile/mage/sh"
 	"github.com/rkennedy/magehelper"
 )
 
@@ -29,7 +28,11 @@ func (*reviveTask) Name() string {
 }
 
 func (fn *reviveTask) Run(ctx context.Context) error {
-	mg.CtxDeps(ctx,
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *reviveTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx,
 		magehelper.Install(fn.reviveBin, reviveImport).ModDir(fn.modDir),
 		magehelper.LoadDependencies,
 	)
@@ -42,8 +45,8 @@ func (fn *reviveTask) Run(ctx context.Context) error {
 		"-config", fn.config,
 		"-set_exit_status",
 		"./...",
-	}, magehelper.Packages[pkg].IndirectGoFiles()...)
-	return sh.RunV(
+	}, magehelper.LoadedPackages()[pkg].IndirectGoFiles()...)
+	return magehelper.RunV(ctx,
 		fn.reviveBin,
 		args...,
 	)
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sqlcHeader is the comment that sqlc puts at the top of every Go file it generates. A build constraint might come
+// before it.
+const sqlcHeader = "// Code generated by sqlc. DO NOT EDIT."
+
+// sqlcGo is the Go code-generation section of a version 2 sqlc.yaml entry.
+type sqlcGo struct {
+	Out string `yaml:"out"`
+}
+
+// sqlcGen is the gen section of a version 2 sqlc.yaml entry.
+type sqlcGen struct {
+	Go *sqlcGo `yaml:"go"`
+}
+
+// sqlcPackage is an entry in sqlc.yaml. Version 1 lists them under packages and puts the output directory in the path
+// field, and version 2 lists them under sql and puts the output directory in gen.go.out.
+type sqlcPackage struct {
+	Path    string     `yaml:"path"`
+	Schema  stringList `yaml:"schema"`
+	Queries stringList `yaml:"queries"`
+	Gen     sqlcGen    `yaml:"gen"`
+}
+
+// out returns the directory where sqlc writes the package's Go code, or the empty string if the entry doesn't generate
+// Go code.
+func (p *sqlcPackage) out() string {
+	if p.Gen.Go != nil {
+		return p.Gen.Go.Out
+	}
+	return p.Path
+}
+
+// sqlcConfig holds the parts of sqlc.yaml that [Sqlc] needs.
+type sqlcConfig struct {
+	Packages []sqlcPackage `yaml:"packages"`
+	SQL      []sqlcPackage `yaml:"sql"`
+}
+
+// entries returns the configuration's entries from either version of the file format.
+func (c *sqlcConfig) entries() []sqlcPackage {
+	return slices.Concat(c.Packages, c.SQL)
+}
+
+// isSQLFile reports whether sqlc reads the given file from a schema or query directory. It skips hidden files and
+// golang-migrate's down migrations.
+func isSQLFile(name string) bool {
+	base := filepath.Base(name)
+	return filepath.Ext(base) == ".sql" && !strings.HasPrefix(base, ".") && !strings.HasSuffix(base, ".down.sql")
+}
+
+// expandSQLPath returns the SQL files that the given path refers to. A directory contributes the SQL files directly
+// inside it.
+func expandSQLPath(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{path}, err
+	}
+	entries, err := os.ReadDir(path)
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	return slices.DeleteFunc(files, func(file string) bool { return !isSQLFile(file) }), err
+}
+
+// expandSQLPattern returns the SQL files that the given glob pattern refers to.
+func expandSQLPattern(pattern string) (files []string, err error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		expanded, err := expandSQLPath(match)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, expanded...)
+	}
+	return files, nil
+}
+
+// sqlFiles returns the SQL files named by the given schema or query paths, which are relative to dir. Paths may be
+// files, directories, or glob patterns, as with sqlc itself.
+func sqlFiles(dir string, paths []string) (files []string, err error) {
+	for _, pattern := range paths {
+		expanded, err := expandSQLPattern(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, expanded...)
+	}
+	return files, nil
+}
+
+// sqlcOutputs returns the Go files that sqlc generated in the given directory, which might not exist yet.
+func sqlcOutputs(dir string) (outputs []string, err error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		generated, err := generatedBy(file, sqlcHeader)
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			outputs = append(outputs, file)
+		}
+	}
+	return outputs, nil
+}
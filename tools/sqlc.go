@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+const sqlcImport = "github.com/sqlc-dev/sqlc/cmd/sqlc"
+
+// SqlcTask is a Mage task that generates Go code from SQL with sqlc.
+type SqlcTask struct {
+	bin    string
+	config string
+	modDir string
+}
+
+var _ mg.Fn = &SqlcTask{}
+
+// Sqlc returns a [mg.Fn] that installs sqlc at the version in go.mod and runs sqlc generate with the configuration in
+// sqlc.yaml. Both version 1 and version 2 of the configuration format are supported.
+//
+// The generated files are the Go files with sqlc's header in each entry's output directory. They're out of date when
+// they're older than the configuration file, the sqlc binary, or any of the schema and query files that the
+// configuration names, whether directly, through a directory, or through a glob pattern. After generating code, the
+// task calls [magehelper.ReloadPackages].
+func Sqlc(bin string) *SqlcTask {
+	return &SqlcTask{
+		bin:    bin,
+		config: "sqlc.yaml",
+	}
+}
+
+// Config sets the configuration file. The default is sqlc.yaml in the current directory. Config returns the SqlcTask.
+func (fn *SqlcTask) Config(config string) *SqlcTask {
+	fn.config = config
+	return fn
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of sqlc this
+// project uses. ModDir returns the SqlcTask.
+func (fn *SqlcTask) ModDir(dir string) *SqlcTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (fn *SqlcTask) Name() string {
+	return fmt.Sprintf("Sqlc generate %s", fn.config)
+}
+
+// ID implements [mg.Fn].
+func (fn *SqlcTask) ID() string {
+	return fmt.Sprintf("magehelper sqlc generate %s", fn.config)
+}
+
+// Run implements [mg.Fn].
+func (fn *SqlcTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *SqlcTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.Install(fn.bin, sqlcImport).ModDir(fn.modDir))
+	var config sqlcConfig
+	if err := readYAML(fn.config, &config); err != nil {
+		return err
+	}
+	stale, err := fn.stale(ctx, config)
+	if err != nil || !stale {
+		return err
+	}
+	if err = runSqlc(ctx, fn.bin, fn.config, "generate"); err != nil {
+		return err
+	}
+	return magehelper.ReloadPackages(ctx)
+}
+
+// inputs returns the files that the generated code depends on.
+func (fn *SqlcTask) inputs(config sqlcConfig) ([]string, error) {
+	dir := filepath.Dir(fn.config)
+	inputs := []string{fn.config, fn.bin}
+	for _, entry := range config.entries() {
+		files, err := sqlFiles(dir, slices.Concat(entry.Schema, entry.Queries))
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, files...)
+	}
+	return inputs, nil
+}
+
+// outputs returns the Go files that sqlc generated for the configuration's entries.
+func (fn *SqlcTask) outputs(config sqlcConfig) (outputs []string, err error) {
+	for _, entry := range config.entries() {
+		if entry.out() == "" {
+			continue
+		}
+		files, err := sqlcOutputs(filepath.Join(filepath.Dir(fn.config), entry.out()))
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, files...)
+	}
+	return outputs, nil
+}
+
+// stale reports whether any generated file is missing or out of date.
+func (fn *SqlcTask) stale(ctx context.Context, config sqlcConfig) (bool, error) {
+	outputs, err := fn.outputs(config)
+	if err != nil {
+		return false, err
+	}
+	if len(outputs) == 0 {
+		magehelper.LogExplain(ctx, "no generated files exist", "config", fn.config)
+		return true, nil
+	}
+	inputs, err := fn.inputs(config)
+	if err != nil {
+		return false, err
+	}
+	return anyStale(ctx, outputs, inputs)
+}
+
+// SqlcCheckTask is a Mage task that checks SQL and the code that sqlc generated from it without changing anything.
+type SqlcCheckTask struct {
+	bin    string
+	config string
+	vet    bool
+	modDir string
+}
+
+var _ mg.Fn = &SqlcCheckTask{}
+
+// SqlcCheck returns a [mg.Fn] that installs sqlc at the version in go.mod and checks the project's SQL, which is
+// suitable for continuous integration. It runs sqlc compile to check the schema and queries, and then sqlc diff to
+// confirm that the committed generated code matches what sqlc would generate. Neither needs a database. The task fails
+// if either command reports problems.
+func SqlcCheck(bin string) *SqlcCheckTask {
+	return &SqlcCheckTask{
+		bin:    bin,
+		config: "sqlc.yaml",
+	}
+}
+
+// Config sets the configuration file. The default is sqlc.yaml in the current directory. Config returns the
+// SqlcCheckTask.
+func (fn *SqlcCheckTask) Config(config string) *SqlcCheckTask {
+	fn.config = config
+	return fn
+}
+
+// Vet makes the task also run sqlc vet, which evaluates the lint rules in the configuration file. Vet only connects to
+// a database when the configuration names one. Vet returns the SqlcCheckTask.
+func (fn *SqlcCheckTask) Vet() *SqlcCheckTask {
+	fn.vet = true
+	return fn
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of sqlc this
+// project uses. ModDir returns the SqlcCheckTask.
+func (fn *SqlcCheckTask) ModDir(dir string) *SqlcCheckTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (fn *SqlcCheckTask) Name() string {
+	return fmt.Sprintf("Sqlc check %s", fn.config)
+}
+
+// ID implements [mg.Fn].
+func (fn *SqlcCheckTask) ID() string {
+	return fmt.Sprintf("magehelper sqlc check %s %t", fn.config, fn.vet)
+}
+
+// Run implements [mg.Fn].
+func (fn *SqlcCheckTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *SqlcCheckTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.Install(fn.bin, sqlcImport).ModDir(fn.modDir))
+	for _, command := range fn.commands() {
+		if err := runSqlc(ctx, fn.bin, fn.config, command); err != nil {
+			return fmt.Errorf("sqlc %s: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// commands returns the sqlc commands that the check runs.
+func (fn *SqlcCheckTask) commands() []string {
+	if fn.vet {
+		return []string{"compile", "diff", "vet"}
+	}
+	return []string{"compile", "diff"}
+}
+
+// runSqlc runs the given sqlc command with the given configuration file.
+func runSqlc(ctx context.Context, bin, config, command string) error {
+	cmd := exec.Command(bin, command, "-f", config)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return magehelper.RunCmd(ctx, cmd)
+}
@@ -0,0 +1,54 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var sqlcDir = filepath.Join("examples", "sqlc")
+
+var _ = Describe("Sqlc", Ordered, func() {
+	schemaDir := filepath.Join(sqlcDir, "db", "schema")
+	genDir := filepath.Join(sqlcDir, "db", "gen")
+
+	BeforeAll(func() {
+		removeAfterAll(genDir)
+		By("generating code")
+		invokeExample(sqlcDir, "generate")
+	})
+
+	It("generates code", func() {
+		Expect(os.ReadFile(filepath.Join(genDir, "query.sql.go"))).To(ContainSubstring("func (q *Queries) GetAuthor("))
+	})
+
+	It("passes the check when generated code is current", func() {
+		invokeExample(sqlcDir, "check")
+	})
+
+	It("doesn't regenerate up-to-date code", func() {
+		Expect(dryRunExample(sqlcDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("ignores down migrations", func() {
+		touchLater(filepath.Join(schemaDir, "001_authors.down.sql"))
+		Expect(dryRunExample(sqlcDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("regenerates code when the schema changes", func() {
+		touchLater(filepath.Join(schemaDir, "001_authors.sql"))
+		Expect(dryRunExample(sqlcDir, "generate")).To(MatchRegexp(`would run.*sqlc generate`))
+	})
+
+	It("fails the check when generated code differs", func() {
+		models := filepath.Join(genDir, "models.go")
+		content, err := os.ReadFile(models)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(models, append(content, "// edited\n"...), 0o600)).To(Succeed())
+
+		_, status := invokeExampleStatus(sqlcDir, "check")
+		Expect(status).NotTo(Equal(0))
+	})
+})
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/iters"
+)
+
+// StringerPackageTask is a [mg.Fn] implementation that runs the stringer utility to generate code for any number of
+// enum types in a package.
+type StringerPackageTask struct {
+	stringerBin string
+	pkg         string
+	typeNames   []string
+
+	output      string
+	lineComment bool
+	trimPrefix  string
+	tags        []string
+
+	modDir string
+}
+
+var _ mg.Fn = &StringerPackageTask{}
+
+// StringerPackage returns a [mg.Fn] object suitable for using with [mg.Deps] and similar. When resolved, the object
+// will run the stringer utility once to generate code for all the given types in the given package, which must be one
+// of the packages loaded by [magehelper.LoadDependencies]. Unlike [Stringer], it doesn't need a list of input files;
+// the package's Go files determine whether the generated file is out of date. The generated file goes in the
+// package's directory with stringer's default name, <type>_string.go, after the first type, unless
+// [StringerPackageTask.Output] says otherwise. The stringer utility is installed if it's not present or if it's out of
+// date. After generating code, the task calls [magehelper.ReloadPackages].
+func StringerPackage(stringerBin, pkg string, typeNames ...string) *StringerPackageTask {
+	return &StringerPackageTask{
+		stringerBin: stringerBin,
+		pkg:         pkg,
+		typeNames:   typeNames,
+	}
+}
+
+// ID implements [mg.Fn].
+func (fn *StringerPackageTask) ID() string {
+	return fmt.Sprintf("magehelper stringer %s %s", fn.pkg, strings.Join(fn.typeNames, listSeparator))
+}
+
+// Name implements [mg.Fn].
+func (fn *StringerPackageTask) Name() string {
+	return fmt.Sprintf("Stringer %s %s", fn.pkg, strings.Join(fn.typeNames, ", "))
+}
+
+// ModDir indicates the directory where a go.mod file exists specifying which version of the string module to use for
+// this task.
+func (fn *StringerPackageTask) ModDir(dir string) *StringerPackageTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Output sets the name of the generated file, relative to the current directory. Output returns the
+// StringerPackageTask.
+func (fn *StringerPackageTask) Output(file string) *StringerPackageTask {
+	fn.output = file
+	return fn
+}
+
+// LineComment tells stringer to use the text of each constant's line comment, if it has one, as its string. It
+// corresponds to stringer's -linecomment option. LineComment returns the StringerPackageTask.
+func (fn *StringerPackageTask) LineComment() *StringerPackageTask {
+	fn.lineComment = true
+	return fn
+}
+
+// TrimPrefix tells stringer to remove the given prefix from the constant names to get their strings. It corresponds to
+// stringer's -trimprefix option. TrimPrefix returns the StringerPackageTask.
+func (fn *StringerPackageTask) TrimPrefix(prefix string) *StringerPackageTask {
+	fn.trimPrefix = prefix
+	return fn
+}
+
+// Tags sets the build tags that stringer uses when it loads the package. It corresponds to stringer's -tags option.
+// With tags, all the Go files in the package's directory are dependencies of the generated file, not only the ones in
+// the default build. Tags returns the StringerPackageTask.
+func (fn *StringerPackageTask) Tags(tags ...string) *StringerPackageTask {
+	fn.tags = tags
+	return fn
+}
+
+// Run implements [mg.Fn].
+func (fn *StringerPackageTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *StringerPackageTask) execute(ctx context.Context) error {
+	if len(fn.typeNames) == 0 {
+		return noInputError(fn.pkg)
+	}
+	magehelper.Deps(ctx,
+		magehelper.LoadDependencies,
+		magehelper.Install(fn.stringerBin, stringerImport).ModDir(fn.modDir),
+	)
+	pkg, ok := magehelper.LoadedPackages()[fn.pkg]
+	if !ok {
+		return fmt.Errorf("package %s is not part of this project", fn.pkg)
+	}
+	return fn.generate(ctx, pkg)
+}
+
+// generate runs stringer if the generated file is out of date, and then it reloads the packages so that the new file
+// counts as one of the package's sources.
+func (fn *StringerPackageTask) generate(ctx context.Context, pkg magehelper.Package) error {
+	output := fn.outputFile(pkg)
+	needsUpdate, err := magehelper.Stale(ctx, output, append(fn.inputFiles(pkg, output), fn.stringerBin)...)
+	if err != nil || !needsUpdate {
+		return err
+	}
+	if err = magehelper.RunV(ctx, fn.stringerBin, fn.args(pkg, output)...); err != nil {
+		return err
+	}
+	return magehelper.ReloadPackages(ctx)
+}
+
+// outputFile returns the name of the generated file.
+func (fn *StringerPackageTask) outputFile(pkg magehelper.Package) string {
+	if fn.output != "" {
+		return fn.output
+	}
+	return filepath.Join(pkg.Dir, strings.ToLower(fn.typeNames[0])+"_string.go")
+}
+
+// inputFiles returns the package's Go files, which are the dependencies of the generated file. The generated file is
+// usually one of them already, so it's excluded.
+func (fn *StringerPackageTask) inputFiles(pkg magehelper.Package, output string) []string {
+	files := pkg.GoFiles
+	if len(fn.tags) > 0 {
+		// Some of the ignored files might be part of the build with the given tags.
+		files = slices.Concat(files, pkg.IgnoredGoFiles)
+	}
+	absOutput, _ := filepath.Abs(output)
+	return slices.Collect(iters.Filter(
+		iters.SliceTransform(slices.Values(files), func(file string) string { return filepath.Join(pkg.Dir, file) }),
+		func(file string) bool { return file != absOutput },
+	))
+}
+
+// args returns the command-line arguments for stringer.
+func (fn *StringerPackageTask) args(pkg magehelper.Package, output string) []string {
+	args := []string{"-output", output, "-type", strings.Join(fn.typeNames, listSeparator)}
+	if fn.lineComment {
+		args = append(args, "-linecomment")
+	}
+	args = appendFlag(args, "-trimprefix", fn.trimPrefix)
+	args = appendFlag(args, "-tags", strings.Join(fn.tags, listSeparator))
+	// The package's directory is absolute, so stringer won't mistake it for a package name.
+	return append(args, pkg.Dir)
+}
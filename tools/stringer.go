@@ -6,8 +6,6 @@ import (
 	"path/filepath"
 
 	"github.com/magefile/mage/mg"
-	"github.com/magefile/mage/sh"
-	"github.com/magefile/mage/target"
 	"github.com/rkennedy/magehelper"
 )
 
@@ -50,13 +48,17 @@ func (e noInputError) Error() string {
 
 // Run implements [mg.Fn].
 func (fn *StringerTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *StringerTask) execute(ctx context.Context) error {
 	if len(fn.inputFiles) <= 0 {
 		return noInputError(fn.typeName)
 	}
 
-	mg.CtxDeps(ctx, magehelper.Install(fn.stringerBin, stringerImport).ModDir(fn.modDir))
+	magehelper.Deps(ctx, magehelper.Install(fn.stringerBin, stringerImport).ModDir(fn.modDir))
 
-	needsUpdate, err := target.Dir(fn.destinationFile, append(fn.inputFiles, fn.stringerBin)...)
+	needsUpdate, err := magehelper.StaleDir(ctx, fn.destinationFile, append(fn.inputFiles, fn.stringerBin)...)
 	if err != nil || !needsUpdate {
 		return err
 	}
@@ -72,7 +74,7 @@ func (fn *StringerTask) Run(ctx context.Context) error {
 		packageDir, _ = filepath.Abs(packageDir)
 	}
 
-	return sh.RunV(fn.stringerBin, "-output", fn.destinationFile, "-type", fn.typeName, packageDir)
+	return magehelper.RunV(ctx, fn.stringerBin, "-output", fn.destinationFile, "-type", fn.typeName, packageDir)
 }
 
 // Stringer returns a [mg.Fn] object suitable for using with [mg.Deps] and similar. When resolved, the object will run
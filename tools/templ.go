@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+const templImport = "github.com/a-h/templ/cmd/templ"
+
+// TemplTask is a Mage task that generates Go code from templ components.
+type TemplTask struct {
+	bin    string
+	modDir string
+}
+
+var _ mg.Fn = &TemplTask{}
+
+// Templ returns a [mg.Fn] that installs templ at the version in go.mod and runs templ generate for each .templ file in
+// the directories of the packages loaded by [magehelper.LoadDependencies]. A directory that holds only .templ files
+// isn't a package until it has at least one Go file, so it won't be searched.
+//
+// Each .templ file generates a _templ.go file beside it. The task runs templ only for the files whose generated code is
+// missing or older than the .templ file or the templ binary, so it's cheap to run repeatedly, such as from a file
+// watcher or before every build. After generating code, the task calls [magehelper.ReloadPackages]. For templ's own
+// long-running watch mode, use [TemplWatch].
+func Templ(bin string) *TemplTask {
+	return &TemplTask{
+		bin: bin,
+	}
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of templ this
+// project uses. ModDir returns the TemplTask.
+func (fn *TemplTask) ModDir(dir string) *TemplTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (*TemplTask) Name() string {
+	return "Templ generate"
+}
+
+// ID implements [mg.Fn].
+func (fn *TemplTask) ID() string {
+	return fmt.Sprintf("magehelper templ generate %s", fn.bin)
+}
+
+// Run implements [mg.Fn].
+func (fn *TemplTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *TemplTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx,
+		magehelper.LoadDependencies,
+		magehelper.Install(fn.bin, templImport).ModDir(fn.modDir),
+	)
+	files, err := templFiles()
+	if err != nil {
+		return err
+	}
+	generated, err := fn.generateAll(ctx, files)
+	if err != nil || !generated {
+		return err
+	}
+	return magehelper.ReloadPackages(ctx)
+}
+
+// templFiles returns the .templ files in the loaded packages' directories.
+func templFiles() (files []string, err error) {
+	dirs := mapset.NewThreadUnsafeSet[string]()
+	for pkg := range maps.Values(magehelper.LoadedPackages()) {
+		dirs.Add(pkg.Dir)
+	}
+	for _, dir := range slices.Sorted(slices.Values(dirs.ToSlice())) {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.templ"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// templOutput returns the name of the Go file that templ generates from the given .templ file.
+func templOutput(file string) string {
+	return strings.TrimSuffix(file, ".templ") + "_templ.go"
+}
+
+// generateAll runs templ for each of the given files whose generated code is out of date. It reports whether it ran
+// anything.
+func (fn *TemplTask) generateAll(ctx context.Context, files []string) (generated bool, err error) {
+	for _, file := range files {
+		stale, err := magehelper.Stale(ctx, templOutput(file), file, fn.bin)
+		if err != nil {
+			return false, err
+		}
+		if !stale {
+			continue
+		}
+		if err = fn.generate(ctx, file); err != nil {
+			return false, err
+		}
+		generated = true
+	}
+	return generated, nil
+}
+
+// generate runs templ generate for the given file.
+func (fn *TemplTask) generate(ctx context.Context, file string) error {
+	cmd := exec.Command(fn.bin, "generate", "-f", file)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return magehelper.RunCmd(ctx, cmd)
+}
+
+// TemplWatchTask is a Mage task that runs templ in watch mode.
+type TemplWatchTask struct {
+	bin     string
+	path    string
+	command string
+	proxy   string
+	modDir  string
+}
+
+var _ mg.Fn = &TemplWatchTask{}
+
+// TemplWatch returns a [mg.Fn] that installs templ at the version in go.mod and runs templ generate -watch, which
+// regenerates code whenever a .templ file changes and doesn't return until it's interrupted. Use it as the target of a
+// development loop, such as a mage watch target, and use [Templ] for builds.
+func TemplWatch(bin string) *TemplWatchTask {
+	return &TemplWatchTask{
+		bin:  bin,
+		path: ".",
+	}
+}
+
+// Path sets the directory that templ watches. The default is the current directory. Path returns the TemplWatchTask.
+func (fn *TemplWatchTask) Path(path string) *TemplWatchTask {
+	fn.path = path
+	return fn
+}
+
+// Cmd sets a command for templ to run after generating code, such as one that restarts a server. Cmd returns the
+// TemplWatchTask.
+func (fn *TemplWatchTask) Cmd(command string) *TemplWatchTask {
+	fn.command = command
+	return fn
+}
+
+// Proxy sets the URL of the application server for templ's live-reload proxy to forward to. Proxy returns the
+// TemplWatchTask.
+func (fn *TemplWatchTask) Proxy(url string) *TemplWatchTask {
+	fn.proxy = url
+	return fn
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of templ this
+// project uses. ModDir returns the TemplWatchTask.
+func (fn *TemplWatchTask) ModDir(dir string) *TemplWatchTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (fn *TemplWatchTask) Name() string {
+	return fmt.Sprintf("Templ watch %s", fn.path)
+}
+
+// ID implements [mg.Fn].
+func (fn *TemplWatchTask) ID() string {
+	return fmt.Sprintf("magehelper templ watch %s", fn.path)
+}
+
+// Run implements [mg.Fn].
+func (fn *TemplWatchTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *TemplWatchTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx, magehelper.Install(fn.bin, templImport).ModDir(fn.modDir))
+	cmd := exec.Command(fn.bin, fn.args()...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return magehelper.RunCmd(ctx, cmd)
+}
+
+// args returns the command-line arguments for templ.
+func (fn *TemplWatchTask) args() []string {
+	args := []string{"generate", "-watch", "-path", fn.path}
+	if fn.command != "" {
+		args = append(args, "-cmd", fn.command)
+	}
+	if fn.proxy != "" {
+		args = append(args, "-proxy", fn.proxy)
+	}
+	return args
+}
@@ -0,0 +1,41 @@
+package tools_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var templDir = filepath.Join("examples", "templ")
+
+var _ = Describe("Templ", Ordered, func() {
+	helloTempl := filepath.Join(templDir, "components", "hello.templ")
+
+	BeforeAll(func() {
+		removeAfterAll(
+			filepath.Join(templDir, "page_templ.go"),
+			filepath.Join(templDir, "components", "hello_templ.go"),
+			filepath.Join(templDir, "bin", "example"),
+		)
+		By("building and running the example project")
+		invokeExample(templDir, "test")
+	})
+
+	It("generates code for components in every package", func() {
+		Expect(filepath.Join(templDir, "page_templ.go")).To(BeAnExistingFile())
+		Expect(filepath.Join(templDir, "components", "hello_templ.go")).To(BeAnExistingFile())
+	})
+
+	It("runs nothing when everything is up to date", func() {
+		Expect(dryRunExample(templDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("regenerates only stale components", func() {
+		touchLater(helloTempl)
+
+		output := dryRunExample(templDir, "generate")
+		Expect(output).To(MatchRegexp(`would run.*hello\.templ`))
+		Expect(output).NotTo(MatchRegexp(`would run.*page\.templ`))
+	})
+})
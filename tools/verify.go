@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/magefile/mage/mg"
+	"github.com/magefile/mage/sh"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/iters"
+)
+
+const gitCmd = "git"
+
+// VerifyGeneratedTask is a Mage task that checks whether the committed generated code is up to date.
+type VerifyGeneratedTask struct {
+	generators []mg.Fn
+}
+
+var _ mg.Fn = &VerifyGeneratedTask{}
+
+// VerifyGenerated returns a [mg.Fn] that runs the given code generators, such as [Stringer], [Mockgen], [MockgenAll],
+// and [GoGenerate], and fails if their output differs from what's committed. It's meant for CI, to catch changes that
+// forget to commit regenerated code.
+//
+// The working tree must not have any uncommitted changes to tracked files beforehand. The generators run with a
+// context from [magehelper.WithForce], so they regenerate everything regardless of file times. Afterward, any changes
+// to tracked files, or any new untracked files, mean the committed code was out of date; the task's error includes the
+// unified diff and the names of the new files. The generators must not have already run earlier in the same Mage
+// invocation, or else Mage won't run them again.
+func VerifyGenerated(generators ...mg.Fn) *VerifyGeneratedTask {
+	return &VerifyGeneratedTask{generators: generators}
+}
+
+// Name implements [mg.Fn].
+func (*VerifyGeneratedTask) Name() string {
+	return "Verify generated code"
+}
+
+// ID implements [mg.Fn].
+func (fn *VerifyGeneratedTask) ID() string {
+	ids := slices.Collect(iters.SliceTransform(slices.Values(fn.generators), mg.Fn.ID))
+	return fmt.Sprintf("magehelper verify generated %s", strings.Join(ids, ","))
+}
+
+// Run implements [mg.Fn].
+func (fn *VerifyGeneratedTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *VerifyGeneratedTask) execute(ctx context.Context) error {
+	if err := requireCleanTree(); err != nil {
+		return err
+	}
+	untracked, err := untrackedFiles()
+	if err != nil {
+		return err
+	}
+
+	magehelper.Deps(magehelper.WithForce(ctx), slices.Collect(iters.SliceTransform(slices.Values(fn.generators),
+		func(generator mg.Fn) any { return generator }))...)
+	return checkDrift(untracked)
+}
+
+// requireCleanTree fails if any tracked files have uncommitted changes, which would be indistinguishable from changes
+// made by the generators.
+func requireCleanTree() error {
+	changes, err := sh.Output(gitCmd, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if changes != "" {
+		return fmt.Errorf("cannot verify generated code with uncommitted changes:\n%s", changes)
+	}
+	return nil
+}
+
+// untrackedFiles returns the files that git doesn't track and doesn't ignore.
+func untrackedFiles() ([]string, error) {
+	output, err := sh.Output(gitCmd, "ls-files", "--others", "--exclude-standard")
+	return strings.Fields(output), err
+}
+
+// newUntrackedFiles returns the untracked files that aren't in the given list.
+func newUntrackedFiles(before []string) ([]string, error) {
+	untracked, err := untrackedFiles()
+	return slices.DeleteFunc(untracked, func(file string) bool { return slices.Contains(before, file) }), err
+}
+
+// checkDrift reports the changes to tracked files, and the new untracked files, since the generators ran.
+func checkDrift(untrackedBefore []string) error {
+	diff, err := sh.Output(gitCmd, "diff", "--no-color")
+	if err != nil {
+		return err
+	}
+	newFiles, err := newUntrackedFiles(untrackedBefore)
+	if err != nil {
+		return err
+	}
+	return driftError(diff, newFiles)
+}
+
+// driftError describes the differences between the committed code and the generated code, or returns nil if there
+// aren't any.
+func driftError(diff string, newFiles []string) error {
+	var errs []error
+	if diff != "" {
+		errs = append(errs, fmt.Errorf("generated code differs from committed code:\n%s", diff))
+	}
+	if len(newFiles) > 0 {
+		errs = append(errs, fmt.Errorf("generated files are not committed:\n%s", strings.Join(newFiles, "\n")))
+	}
+	return errors.Join(errs...)
+}
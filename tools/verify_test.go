@@ -0,0 +1,87 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/magefile/mage/sh"
+	"github.com/rkennedy/magehelper"
+	"github.com/rkennedy/magehelper/tools"
+)
+
+const generatedFileMode = 0o644
+
+// fakeGenerator writes content to its output file when the file is out of date with respect to its input.
+type fakeGenerator struct {
+	input   string
+	output  string
+	content string
+}
+
+func (g *fakeGenerator) Name() string {
+	return "fake generator " + g.output
+}
+
+func (g *fakeGenerator) ID() string {
+	return g.Name()
+}
+
+func (g *fakeGenerator) Run(ctx context.Context) error {
+	stale, err := magehelper.Stale(ctx, g.output, g.input)
+	if err != nil || !stale {
+		return err
+	}
+	return os.WriteFile(g.output, []byte(g.content), generatedFileMode)
+}
+
+var _ = Describe("VerifyGenerated", func() {
+	var dir, input, output string
+
+	git := func(args ...string) {
+		Expect(sh.Run("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"},
+			args...)...)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		GinkgoT().Chdir(dir)
+		input = filepath.Join(dir, "input")
+		output = filepath.Join(dir, "output")
+		Expect(os.WriteFile(input, []byte("input\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(output, []byte("v1\n"), 0o644)).To(Succeed())
+		past := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(input, past, past)).To(Succeed())
+
+		git("init", "-q")
+		git("add", ".")
+		git("commit", "-q", "-m", "initial")
+	})
+
+	It("accepts up-to-date generated code", func(ctx context.Context) {
+		Expect(tools.VerifyGenerated(&fakeGenerator{input, output, "v1\n"}).Run(ctx)).To(Succeed())
+	})
+
+	It("regenerates code and reports differences", func(ctx context.Context) {
+		err := tools.VerifyGenerated(&fakeGenerator{input, output, "v2\n"}).Run(ctx)
+		Expect(err).To(SatisfyAll(
+			MatchError(ContainSubstring("-v1")),
+			MatchError(ContainSubstring("+v2")),
+		))
+	})
+
+	It("reports new generated files", func(ctx context.Context) {
+		err := tools.VerifyGenerated(&fakeGenerator{input, filepath.Join(dir, "new"), "new\n"}).Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("generated files are not committed:\nnew")))
+	})
+
+	It("requires a clean working tree", func(ctx context.Context) {
+		Expect(os.WriteFile(input, []byte("changed\n"), 0o644)).To(Succeed())
+		err := tools.VerifyGenerated(&fakeGenerator{input, output, "v1\n"}).Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("uncommitted changes")))
+	})
+})
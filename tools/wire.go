@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper"
+)
+
+const (
+	wireImport = "github.com/google/wire/cmd/wire"
+	wireTag    = "wireinject"
+	wireOutput = "wire_gen.go"
+)
+
+// wireInjector is a package that declares Wire injectors.
+type wireInjector struct {
+	// pkg is the package as seen with the wireinject tag.
+	pkg magehelper.Package
+}
+
+// output returns the path of the file that wire gen writes for the package.
+func (inj *wireInjector) output() string {
+	return filepath.Join(inj.pkg.Dir, wireOutput)
+}
+
+// inputs returns the files that the generated code depends on: the package's files as seen with the wireinject tag,
+// which include the injector files, and the source files of the local packages they import, directly or indirectly.
+func (inj *wireInjector) inputs() []string {
+	inputs := mapset.NewThreadUnsafeSet[string]()
+	for _, file := range inj.pkg.GoFiles {
+		inputs.Add(filepath.Join(inj.pkg.Dir, file))
+	}
+	for _, imported := range inj.pkg.Imports {
+		inputs.Append(magehelper.GetDependencies(imported,
+			magehelper.Package.SourceFiles,
+			magehelper.Package.SourceImportPackages)...)
+	}
+	return slices.Sorted(slices.Values(inputs.ToSlice()))
+}
+
+// hasInjectorFiles reports whether the tagged package has files that the untagged package doesn't include. The
+// untagged package doesn't exist when every file is an injector file.
+func hasInjectorFiles(untagged, tagged magehelper.Package) bool {
+	return slices.ContainsFunc(tagged.GoFiles, func(file string) bool {
+		return !slices.Contains(untagged.GoFiles, file)
+	})
+}
+
+// wireInjectors returns the packages that have files built only with the wireinject tag. It loads the packages again
+// with that tag because ordinary loading ignores those files. [magehelper.LoadDependencies] must have run already.
+func wireInjectors() (injectors []wireInjector, err error) {
+	tagged, err := magehelper.ListPackages(wireTag)
+	if err != nil {
+		return nil, err
+	}
+	for _, importPath := range slices.Sorted(maps.Keys(tagged)) {
+		if hasInjectorFiles(magehelper.LoadedPackages()[importPath], tagged[importPath]) {
+			injectors = append(injectors, wireInjector{pkg: tagged[importPath]})
+		}
+	}
+	return injectors, nil
+}
+
+// runWire runs the given wire command on the given packages.
+func runWire(ctx context.Context, bin, command string, injectors []wireInjector) error {
+	args := []string{command}
+	for _, inj := range injectors {
+		args = append(args, inj.pkg.ImportPath)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return magehelper.RunCmd(ctx, cmd)
+}
+
+// WireTask is a Mage task that generates dependency-injection code with Wire.
+type WireTask struct {
+	bin    string
+	modDir string
+}
+
+var _ mg.Fn = &WireTask{}
+
+// Wire returns a [mg.Fn] that installs Wire at the version in go.mod and runs wire gen for each package that declares
+// injectors, which are the packages with files that only build with the wireinject tag.
+//
+// Each such package's wire_gen.go is out of date when it's older than the wire binary, any of the package's files as
+// seen with the wireinject tag, or any source file of the local packages that those files import, directly or
+// indirectly, which is where the providers come from. After generating code, the task calls
+// [magehelper.ReloadPackages].
+func Wire(bin string) *WireTask {
+	return &WireTask{
+		bin: bin,
+	}
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of Wire this
+// project uses. ModDir returns the WireTask.
+func (fn *WireTask) ModDir(dir string) *WireTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (*WireTask) Name() string {
+	return "Wire gen"
+}
+
+// ID implements [mg.Fn].
+func (fn *WireTask) ID() string {
+	return fmt.Sprintf("magehelper wire gen %s", fn.bin)
+}
+
+// Run implements [mg.Fn].
+func (fn *WireTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *WireTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx,
+		magehelper.LoadDependencies,
+		magehelper.Install(fn.bin, wireImport).ModDir(fn.modDir),
+	)
+	injectors, err := wireInjectors()
+	if err != nil {
+		return err
+	}
+	stale, err := fn.staleInjectors(ctx, injectors)
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+	if err = runWire(ctx, fn.bin, "gen", stale); err != nil {
+		return err
+	}
+	return magehelper.ReloadPackages(ctx)
+}
+
+// staleInjectors returns the injector packages whose generated code is missing or out of date.
+func (fn *WireTask) staleInjectors(ctx context.Context, injectors []wireInjector) (result []wireInjector, err error) {
+	for _, inj := range injectors {
+		stale, err := magehelper.Stale(ctx, inj.output(), append(inj.inputs(), fn.bin)...)
+		if err != nil {
+			return nil, err
+		}
+		if stale {
+			result = append(result, inj)
+		}
+	}
+	return result, nil
+}
+
+// WireCheckTask is a Mage task that checks Wire's dependency graphs and generated code without changing anything.
+type WireCheckTask struct {
+	bin    string
+	modDir string
+}
+
+var _ mg.Fn = &WireCheckTask{}
+
+// WireCheck returns a [mg.Fn] that installs Wire at the version in go.mod and checks each package that declares
+// injectors, which is suitable for continuous integration. It runs wire check to report errors in the dependency
+// graphs, and then wire diff to confirm that the committed wire_gen.go files match what wire gen would generate. The
+// task fails if either command reports problems.
+func WireCheck(bin string) *WireCheckTask {
+	return &WireCheckTask{
+		bin: bin,
+	}
+}
+
+// ModDir sets the directory where this task will look for a go.mod file that specifies which version of Wire this
+// project uses. ModDir returns the WireCheckTask.
+func (fn *WireCheckTask) ModDir(dir string) *WireCheckTask {
+	fn.modDir = dir
+	return fn
+}
+
+// Name implements [mg.Fn].
+func (*WireCheckTask) Name() string {
+	return "Wire check"
+}
+
+// ID implements [mg.Fn].
+func (fn *WireCheckTask) ID() string {
+	return fmt.Sprintf("magehelper wire check %s", fn.bin)
+}
+
+// Run implements [mg.Fn].
+func (fn *WireCheckTask) Run(ctx context.Context) error {
+	return magehelper.RunTask(ctx, fn, fn.execute)
+}
+
+func (fn *WireCheckTask) execute(ctx context.Context) error {
+	magehelper.Deps(ctx,
+		magehelper.LoadDependencies,
+		magehelper.Install(fn.bin, wireImport).ModDir(fn.modDir),
+	)
+	injectors, err := wireInjectors()
+	if err != nil || len(injectors) == 0 {
+		return err
+	}
+	for _, command := range []string{"check", "diff"} {
+		if err = runWire(ctx, fn.bin, command, injectors); err != nil {
+			return fmt.Errorf("wire %s: %w", command, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var wireDir = filepath.Join("examples", "wire")
+
+var _ = Describe("Wire", Ordered, func() {
+	wireGen := filepath.Join(wireDir, "wire_gen.go")
+	provider := filepath.Join(wireDir, "greet", "greet.go")
+
+	BeforeAll(func() {
+		removeAfterAll(wireGen, filepath.Join(wireDir, "bin", "example"))
+		By("building and running the example project")
+		invokeExample(wireDir, "test")
+	})
+
+	It("generates the injector", func() {
+		Expect(os.ReadFile(wireGen)).To(ContainSubstring("func initializeGreeter("))
+	})
+
+	It("passes the check when generated code is current", func() {
+		invokeExample(wireDir, "check")
+	})
+
+	It("doesn't regenerate up-to-date code", func() {
+		Expect(dryRunExample(wireDir, "generate")).NotTo(ContainSubstring("would run"))
+	})
+
+	It("regenerates code when a provider package changes", func() {
+		touchLater(provider)
+		Expect(dryRunExample(wireDir, "generate")).To(MatchRegexp(`would run.*wire gen .*/examples/wire\b`))
+	})
+
+	It("fails the check when generated code differs", func() {
+		content, err := os.ReadFile(wireGen)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(wireGen, append(content, "// edited\n"...), 0o600)).To(Succeed())
+
+		_, status := invokeExampleStatus(wireDir, "check")
+		Expect(status).NotTo(Equal(0))
+	})
+})
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wordSeparators are the characters that separate the words of a command line.
+const wordSeparators = " \t"
+
+// splitWords splits a command line into words the way go generate does. Spaces and tabs separate words, and a word that
+// starts with a double quote is a Go string literal, so it may contain spaces and escape sequences.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	for line = strings.TrimLeft(line, wordSeparators); line != ""; line = strings.TrimLeft(line, wordSeparators) {
+		word, rest, err := nextWord(line)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, word)
+		line = rest
+	}
+	return words, nil
+}
+
+// nextWord returns the first word of a line, which doesn't start with a separator, and the rest of the line after it.
+func nextWord(line string) (word, rest string, err error) {
+	if line[0] != '"' {
+		end := strings.IndexAny(line, wordSeparators)
+		if end < 0 {
+			return line, "", nil
+		}
+		return line[:end], line[end:], nil
+	}
+	quoted, err := strconv.QuotedPrefix(line)
+	if err != nil {
+		return "", "", fmt.Errorf("bad quoted string in %q: %w", line, err)
+	}
+	word, err = strconv.Unquote(quoted)
+	return word, line[len(quoted):], err
+}
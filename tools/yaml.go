@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"iter"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stringList is a configuration field that holds either a single string or a list of strings.
+type stringList []string
+
+// UnmarshalYAML implements [yaml.Unmarshaler].
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(l))
+}
+
+// readYAML decodes the given YAML file into v.
+func readYAML(file string, v any) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(content, v)
+}
+
+// nodesPerEntry is the number of nodes for each entry of a YAML mapping node; its content alternates between keys and
+// values.
+const nodesPerEntry = 2
+
+// mappingEntries yields the key and value nodes of a YAML mapping node.
+func mappingEntries(node *yaml.Node) iter.Seq2[*yaml.Node, *yaml.Node] {
+	return func(yield func(*yaml.Node, *yaml.Node) bool) {
+		for entry := range slices.Chunk(node.Content, nodesPerEntry) {
+			if !yield(entry[0], entry[1]) {
+				return
+			}
+		}
+	}
+}
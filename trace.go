@@ -0,0 +1,233 @@
+package magehelper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/magefile/mage/mg"
+	"github.com/rkennedy/magehelper/iters"
+)
+
+// traceEvent is a single complete ("X") event in the Chrome trace-event format, which Perfetto and chrome://tracing
+// can both display.
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur"`
+	PID       int            `json:"pid"`
+	TID       int            `json:"tid"`
+	Args      map[string]any `json:"args"`
+}
+
+// tracer collects events for every task run through [RunTask] while tracing is enabled. Each running task occupies a
+// lane, which becomes the event's thread ID, so the trace viewer shows concurrent tasks side by side.
+type tracer struct {
+	mu      sync.Mutex
+	enabled bool
+	start   time.Time
+	lanes   []bool
+	events  []traceEvent
+}
+
+var globalTracer tracer
+
+// taskRecord is the state of one running task. It travels in the context so that [Deps] can attribute dependencies to
+// the task that declared them.
+type taskRecord struct {
+	name  string
+	id    string
+	start time.Time
+	lane  int
+
+	mu   sync.Mutex
+	deps []string
+}
+
+type taskKey struct{}
+
+func (t *tracer) acquireLane() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lane := slices.Index(t.lanes, false)
+	if lane < 0 {
+		lane = len(t.lanes)
+		t.lanes = append(t.lanes, true)
+	}
+	t.lanes[lane] = true
+	return lane
+}
+
+func (t *tracer) finish(rec *taskRecord, err error) {
+	end := time.Now()
+	args := map[string]any{
+		"id":          rec.id,
+		"deps":        rec.deps,
+		"exit_status": mg.ExitStatus(err),
+	}
+	if err != nil {
+		args["error"] = err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lanes[rec.lane] = false
+	if !t.enabled {
+		return
+	}
+	t.events = append(t.events, traceEvent{
+		Name:      rec.name,
+		Category:  "magehelper",
+		Phase:     "X",
+		Timestamp: rec.start.Sub(t.start).Microseconds(),
+		Duration:  end.Sub(rec.start).Microseconds(),
+		PID:       os.Getpid(),
+		TID:       rec.lane,
+		Args:      args,
+	})
+}
+
+// StartTrace discards any previously recorded trace and begins recording the start and end of every task run through
+// [RunTask]. All magehelper tasks do that, and [Traced] makes other tasks do it, too. Call [WriteTrace] or
+// [WriteTraceFile] at the end of the build to save the results.
+func StartTrace() {
+	globalTracer.mu.Lock()
+	defer globalTracer.mu.Unlock()
+	globalTracer.enabled = true
+	globalTracer.start = time.Now()
+	globalTracer.events = nil
+}
+
+// WriteTrace writes the recorded trace to w as a Chrome trace-event JSON document. Load the result in Perfetto or
+// chrome://tracing to see which tasks ran concurrently and which formed the critical path.
+func WriteTrace(w io.Writer) error {
+	globalTracer.mu.Lock()
+	events := slices.Clone(globalTracer.events)
+	globalTracer.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(map[string]any{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+// WriteTraceFile is like [WriteTrace], but it writes the trace to the named file, replacing any existing file.
+func WriteTraceFile(name string) (err error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return WriteTrace(f)
+}
+
+// currentTask returns the record of the task running in ctx, or nil if ctx didn't come from [RunTask].
+func currentTask(ctx context.Context) *taskRecord {
+	rec, ok := ctx.Value(taskKey{}).(*taskRecord)
+	if !ok {
+		return nil
+	}
+	return rec
+}
+
+// RunTask runs body as the implementation of fn's Run method. It records the task in the context given to body so that
+// [Deps] can attribute dependencies to it, and when tracing is active, it records the task's start and end times,
+// dependencies, and exit status.
+func RunTask(ctx context.Context, fn mg.Fn, body func(context.Context) error) (err error) {
+	rec := &taskRecord{
+		name:  fn.Name(),
+		id:    fn.ID(),
+		start: time.Now(),
+		lane:  globalTracer.acquireLane(),
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			// Mage reports a failed dependency by panicking; record it before passing it along.
+			globalTracer.finish(rec, fmt.Errorf("%v", v))
+			panic(v)
+		}
+		globalTracer.finish(rec, err)
+	}()
+	return body(context.WithValue(ctx, taskKey{}, rec))
+}
+
+// depName returns a display name for something that can be passed to [mg.CtxDeps].
+func depName(dep any) string {
+	fn, ok := dep.(mg.Fn)
+	if !ok {
+		fn = mg.F(dep)
+	}
+	if id := fn.ID(); id != "" && id != "[]" {
+		return fmt.Sprintf("%s (%s)", fn.Name(), id)
+	}
+	return fn.Name()
+}
+
+// Deps is like [mg.CtxDeps], but it also records, for tracing, that the task running in ctx depends on deps.
+func Deps(ctx context.Context, deps ...any) {
+	if rec := currentTask(ctx); rec != nil {
+		names := slices.Collect(iters.SliceTransform(slices.Values(deps), depName))
+		rec.mu.Lock()
+		rec.deps = append(rec.deps, names...)
+		rec.mu.Unlock()
+	}
+	mg.CtxDeps(ctx, deps...)
+}
+
+// funcTask is a [mg.Fn] that lets ordinary functions, such as [Build] and [LoadDependencies], run through [RunTask].
+type funcTask struct {
+	name string
+	id   string
+}
+
+// newFuncTask returns a funcTask with the given name for running fn with the given arguments. Like [mg.F], it builds
+// the ID from the function's name and its arguments, so different functions, or one function with different arguments,
+// stay distinct in traces and under [mg.Deps].
+func newFuncTask(name string, fn any, args ...any) funcTask {
+	fnName := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	id, err := json.Marshal(append([]any{}, args...))
+	if err != nil {
+		id = fmt.Appendf(nil, "%v", args)
+	}
+	return funcTask{name: name, id: fmt.Sprintf("%s(%s)", fnName, id)}
+}
+
+func (fn funcTask) Name() string {
+	return fn.name
+}
+
+func (fn funcTask) ID() string {
+	return fn.id
+}
+
+func (funcTask) Run(context.Context) error {
+	return nil
+}
+
+type tracedTask struct {
+	mg.Fn
+}
+
+// Run implements [mg.Fn].
+func (fn tracedTask) Run(ctx context.Context) error {
+	return RunTask(ctx, fn.Fn, fn.Fn.Run)
+}
+
+// Traced wraps fn so that it gets traced like the tasks that magehelper provides. Use it for a project's own tasks to
+// see them in the trace alongside magehelper's. Don't wrap magehelper's own tasks; they're already traced.
+func Traced(fn mg.Fn) mg.Fn {
+	return tracedTask{fn}
+}
@@ -0,0 +1,88 @@
+package magehelper_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkennedy/magehelper"
+)
+
+type traceTestTask struct {
+	name string
+	deps []any
+	err  error
+}
+
+func (fn *traceTestTask) Name() string {
+	return fn.name
+}
+
+func (*traceTestTask) ID() string {
+	return ""
+}
+
+func (fn *traceTestTask) Run(ctx context.Context) error {
+	magehelper.Deps(ctx, fn.deps...)
+	return fn.err
+}
+
+type traceArgs struct {
+	ID         string
+	Deps       []string
+	ExitStatus int `json:"exit_status"`
+}
+
+type traceEvent struct {
+	Name  string
+	Phase string `json:"ph"`
+	Args  traceArgs
+}
+
+type traceDocument struct {
+	TraceEvents []traceEvent
+}
+
+var _ = Describe("Trace", func() {
+	It("records tasks, dependencies, and exit status", func(ctx context.Context) {
+		inner := magehelper.Traced(&traceTestTask{name: "trace-inner"})
+		outer := magehelper.Traced(&traceTestTask{name: "trace-outer", deps: []any{inner}, err: errors.New("failed")})
+
+		magehelper.StartTrace()
+		Expect(outer.Run(ctx)).To(MatchError("failed"))
+
+		var buf bytes.Buffer
+		Expect(magehelper.WriteTrace(&buf)).To(Succeed())
+		var trace traceDocument
+		Expect(json.Unmarshal(buf.Bytes(), &trace)).To(Succeed())
+		Expect(trace.TraceEvents).To(ContainElements(
+			SatisfyAll(
+				HaveField("Name", "trace-inner"),
+				HaveField("Phase", "X"),
+				HaveField("Args.ExitStatus", 0),
+			),
+			SatisfyAll(
+				HaveField("Name", "trace-outer"),
+				HaveField("Args.Deps", ConsistOf("trace-inner")),
+				HaveField("Args.ExitStatus", 1),
+			),
+		))
+	})
+	It("identifies wrapped functions by name", func(ctx context.Context) {
+		magehelper.StartTrace()
+		Expect(magehelper.LoadDependencies(ctx)).To(Succeed())
+
+		var buf bytes.Buffer
+		Expect(magehelper.WriteTrace(&buf)).To(Succeed())
+		var trace traceDocument
+		Expect(json.Unmarshal(buf.Bytes(), &trace)).To(Succeed())
+		Expect(trace.TraceEvents).To(ContainElement(SatisfyAll(
+			HaveField("Name", "Load dependencies"),
+			HaveField("Args.ID", "github.com/rkennedy/magehelper.LoadDependencies([])"),
+		)))
+	})
+})